@@ -12,6 +12,13 @@ type ClientConfig struct {
 	odkClient odk.APIClient
 	oksAuth   *context.Context
 	oksClient oks.APIClient
+	ipCache   *ipListCache
+	resolver  hostResolver
+}
+
+// hostResolver is implemented by net.Resolver, tests can replace it with a stub.
+type hostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
 }
 
 const ( // values not used in .tf files
@@ -61,6 +68,10 @@ const ( // <export> values exported to .tf files
 	DICT_AUTOSCALING_OFF = 184
 	DICT_AUTOSCALING_ON  = 185
 
+	// Dictionary #70
+	DICT_SYSTEM_CATEGORY_WINDOWS = 1276
+	DICT_SYSTEM_CATEGORY_LINUX   = 1277
+
 	// Dictionary #77
 	DICT_LB_ALGORITHM_LEAST_CONNECTION    = 281
 	DICT_LB_ALGORITHM_LEAST_RESPONSE_TIME = 282
@@ -92,6 +103,9 @@ const ( // <export> values exported to .tf files
 	DICT_SHARE_TYPE_LINUX   = 1411
 	DICT_SHARE_TYPE_WINDOWS = 1412
 
+	// Dictionary #166
+	DICT_HTTP_METHOD_GET = 1440
+
 	// Dictionary #167
 	DICT_ETHERNET_CONTROLLER_E1000   = 1442
 	DICT_ETHERNET_CONTROLLER_VMXNET3 = 1443
@@ -105,6 +119,9 @@ const ( // <export> values exported to .tf files
 	DICT_PROXY_PROTOCOL_NONE = 1861
 	DICT_PROXY_PROTOCOL_V1   = 1862
 	DICT_PROXY_PROTOCOL_V2   = 1863
+
+	// Health check notification time, dictionary number isn't published by ODK
+	DICT_NOTIFICATION_TIME_DEFAULT = 1594
 ) // </export>
 
 type DCConfig struct {
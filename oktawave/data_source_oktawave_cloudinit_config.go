@@ -0,0 +1,152 @@
+package oktawave
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+type cloudInitPart struct {
+	contentType string
+	content     string
+	filename    string
+	mergeType   string
+}
+
+func dataSourceCloudInitConfig() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceCloudInitConfigRead,
+		Schema: map[string]*schema.Schema{
+			"part": {
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"content_type": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "text/plain",
+							Description: "MIME type of this part, e.g. text/x-shellscript or text/cloud-config.",
+						},
+						"content": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Body of this part.",
+						},
+						"filename": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Filename reported in Content-Disposition header.",
+						},
+						"merge_type": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Value of X-Merge-Type header, controls how cloud-init merges this part.",
+						},
+					},
+				},
+				Description: "Parts of the multi-part document, in order.",
+			},
+			"gzip": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Compress rendered document with gzip. Requires base64_encode.",
+			},
+			"base64_encode": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Encode rendered document with base64, so it can be used as user_data_base64 of instance.",
+			},
+			"boundary": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "MIMEBOUNDARY",
+				Description: "MIME boundary separating parts.",
+			},
+			"rendered": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Rendered multi-part document.",
+			},
+		},
+		Description: "Renders multi-part MIME document consumed by cloud-init.",
+	}
+}
+
+func dataSourceCloudInitConfigRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "rendering cloud-init config")
+
+	useGzip := d.Get("gzip").(bool)
+	base64Encode := d.Get("base64_encode").(bool)
+	if useGzip && !base64Encode {
+		return diag.Errorf("gzip requires base64_encode, compressed document can't be stored as plain string")
+	}
+
+	var parts []cloudInitPart
+	for _, rawPart := range d.Get("part").([]interface{}) {
+		part := rawPart.(map[string]interface{})
+		parts = append(parts, cloudInitPart{
+			contentType: part["content_type"].(string),
+			content:     part["content"].(string),
+			filename:    part["filename"].(string),
+			mergeType:   part["merge_type"].(string),
+		})
+	}
+
+	rendered, err := renderCloudInitConfig(parts, d.Get("boundary").(string), useGzip, base64Encode)
+	if err != nil {
+		return diag.Errorf("Can't render cloud-init config. %s", err)
+	}
+
+	if d.Set("rendered", rendered) != nil {
+		return diag.Errorf("Can't set rendered document")
+	}
+	d.SetId(hashUserData(rendered))
+	return nil
+}
+
+func renderCloudInitConfig(parts []cloudInitPart, boundary string, useGzip bool, base64Encode bool) (string, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=\"%s\"\r\nMIME-Version: 1.0\r\n\r\n", boundary)
+
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return "", err
+	}
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("MIME-Version", "1.0")
+		header.Set("Content-Transfer-Encoding", "7bit")
+		if part.filename != "" {
+			header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", part.filename))
+		}
+		if part.mergeType != "" {
+			header.Set("X-Merge-Type", part.mergeType)
+		}
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err := partWriter.Write([]byte(part.content)); err != nil {
+			return "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	if !base64Encode {
+		return buf.String(), nil
+	}
+	return encodeUserData(buf.Bytes(), useGzip)
+}
@@ -0,0 +1,106 @@
+package oktawave
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestOktawave_DataSource_CloudInitConfig(t *testing.T) {
+	dataSourceConfig := `
+data "oktawave_cloudinit_config" "config" {
+	base64_encode = false
+	part {
+		content_type = "text/cloud-config"
+		content = "packages: [nginx]"
+		filename = "init.cfg"
+	}
+	part {
+		content_type = "text/x-shellscript"
+		content = "echo hello"
+	}
+}
+`
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: dataSourceConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.oktawave_cloudinit_config.config", "rendered"),
+					resource.TestCheckResourceAttrSet("data.oktawave_cloudinit_config.config", "id"),
+				),
+			},
+		},
+	})
+}
+
+func TestRenderCloudInitConfig(t *testing.T) {
+	parts := []cloudInitPart{
+		{contentType: "text/cloud-config", content: "packages: [nginx]", filename: "init.cfg", mergeType: "list(append)+dict(recurse_array)+str()"},
+		{contentType: "text/x-shellscript", content: "#!/bin/sh\necho hello"},
+	}
+
+	rendered, err := renderCloudInitConfig(parts, "MIMEBOUNDARY", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"Content-Type: multipart/mixed; boundary=\"MIMEBOUNDARY\"",
+		"Content-Type: text/cloud-config",
+		"Content-Disposition: attachment; filename=\"init.cfg\"",
+		"X-Merge-Type: list(append)+dict(recurse_array)+str()",
+		"#!/bin/sh\necho hello",
+		"--MIMEBOUNDARY--",
+	} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("rendered document doesn't contain %q:\n%s", expected, rendered)
+		}
+	}
+
+	encoded, err := renderCloudInitConfig(parts, "MIMEBOUNDARY", true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeUserData(encoded, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != rendered {
+		t.Errorf("gzip round trip mismatch:\n%s\n%s", decoded, rendered)
+	}
+}
+
+func TestUserDataEncoding(t *testing.T) {
+	userData := "#!/bin/sh\necho hello"
+
+	plain, err := encodeUserData([]byte(userData), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain != base64.StdEncoding.EncodeToString([]byte(userData)) {
+		t.Errorf("unexpected encoding %q", plain)
+	}
+
+	for _, useGzip := range []bool{false, true} {
+		encoded, err := encodeUserData([]byte(userData), useGzip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := decodeUserData(encoded, useGzip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(decoded) != userData {
+			t.Errorf("round trip with gzip=%v returned %q", useGzip, decoded)
+		}
+	}
+
+	if userDataStateFunc(userData) != hashUserData(userData) || len(hashUserData(userData)) != 40 {
+		t.Errorf("unexpected user data hash %q", userDataStateFunc(userData))
+	}
+}
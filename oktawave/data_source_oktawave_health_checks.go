@@ -0,0 +1,125 @@
+package oktawave
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/oktawave-code/odk"
+)
+
+func getHealthCheckDataSourceSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"id": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"name": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"address": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"interval": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"service_type_id": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"service_type_label": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"state_id": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"state_label": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"paused": {
+			Type:     schema.TypeBool,
+			Computed: true,
+		},
+		"suspended": {
+			Type:     schema.TypeBool,
+			Computed: true,
+		},
+		"details_location": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"description": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"last_valid_check": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"last_invalid_check": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+	}
+}
+
+func dataSourceHealthChecks() *schema.Resource {
+	name := "items"
+	dataSourceSchema := makeDataSourceSchema(name, getHealthCheckDataSourceSchema)
+	dataSourceReadFunction := makeDataSourceRead(name, dataSourceSchema, getHealthChecksList, mapRawHealthCheckToDataSourceModel)
+	return &schema.Resource{
+		ReadContext: dataSourceReadFunction,
+		Schema:      dataSourceSchema,
+	}
+}
+
+func getHealthChecksList(config *ClientConfig) ([]odk.HealthCheck, error) {
+	client := config.odkClient
+	auth := config.odkAuth
+
+	params := map[string]interface{}{
+		"pageSize": int32(math.MaxInt16),
+		"orderBy":  "Name",
+	}
+	list, _, err := client.WatchApi.WatchGetHealthChecks(*auth, params)
+	if err != nil {
+		return nil, fmt.Errorf("get health checks request failed, caused by: %s", err)
+	}
+
+	return list.Items, nil
+}
+
+func mapRawHealthCheckToDataSourceModel(healthCheck odk.HealthCheck) (map[string]interface{}, error) {
+	result := map[string]interface{}{
+		"id":                 healthCheck.Id,
+		"name":               healthCheck.Name,
+		"address":            healthCheck.Address,
+		"interval":           healthCheck.Interval,
+		"paused":             healthCheck.Paused,
+		"suspended":          healthCheck.Suspended,
+		"details_location":   healthCheck.DetailsLocation,
+		"description":        healthCheck.Description,
+		"last_valid_check":   healthCheck.LastValidCheck.String(),
+		"last_invalid_check": healthCheck.LastInvalidCheck.String(),
+		// zero values keep filters working for health checks without dictionary items
+		"service_type_id":    int32(0),
+		"service_type_label": "",
+		"state_id":           int32(0),
+		"state_label":        "",
+	}
+	if healthCheck.ServiceType != nil {
+		result["service_type_id"] = healthCheck.ServiceType.Id
+		result["service_type_label"] = healthCheck.ServiceType.Label
+	}
+	if healthCheck.State != nil {
+		result["state_id"] = healthCheck.State.Id
+		result["state_label"] = healthCheck.State.Label
+	}
+	return result, nil
+}
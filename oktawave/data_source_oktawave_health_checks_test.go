@@ -0,0 +1,39 @@
+package oktawave
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestOktawave_DataSource_HealthChecks(t *testing.T) {
+	resourcesConfig := testAccHealthCheckConfig("test-health-check", 60)
+
+	dataSourceConfig := `
+data "oktawave_health_checks" "health_checks" {
+	filter {
+		key = "name"
+		values = ["test-health-check"]
+	}
+}
+	`
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckHealthCheckDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: resourcesConfig,
+			},
+			{
+				Config: resourcesConfig + dataSourceConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.oktawave_health_checks.health_checks", "items.#", "1"),
+					resource.TestCheckResourceAttrPair("data.oktawave_health_checks.health_checks", "items.0.id", "oktawave_health_check.test-health-check", "id"),
+					resource.TestCheckResourceAttr("data.oktawave_health_checks.health_checks", "items.0.paused", "true"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,106 @@
+package oktawave
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/oktawave-code/odk"
+)
+
+func getInstanceSnapshotDataSourceSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"id": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"name": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"description": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"instance_id": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"creation_date": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"last_change_date": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"creation_user_id": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"is_current": {
+			Type:     schema.TypeBool,
+			Computed: true,
+		},
+		"is_system": {
+			Type:     schema.TypeBool,
+			Computed: true,
+		},
+		"parent_snapshot_id": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+	}
+}
+
+func dataSourceInstanceSnapshots() *schema.Resource {
+	name := "items"
+	dataSourceSchema := makeDataSourceSchema(name, getInstanceSnapshotDataSourceSchema)
+	dataSourceReadFunction := makeDataSourceRead(name, dataSourceSchema, getInstanceSnapshotsList, mapRawInstanceSnapshotToDataSourceModel)
+	return &schema.Resource{
+		ReadContext: dataSourceReadFunction,
+		Schema:      dataSourceSchema,
+	}
+}
+
+func getInstanceSnapshotsList(config *ClientConfig) ([]odk.Snapshot, error) {
+	client := config.odkClient
+	auth := config.odkAuth
+
+	params := map[string]interface{}{
+		"pageSize": int32(math.MaxInt16),
+		"orderBy":  "CreationDate",
+	}
+	list, _, err := client.OCISnapshotsApi.SnapshotsGet(*auth, params)
+	if err != nil {
+		return nil, fmt.Errorf("get snapshots request failed, caused by: %s", err)
+	}
+
+	return list.Items, nil
+}
+
+func mapRawInstanceSnapshotToDataSourceModel(snapshot odk.Snapshot) (map[string]interface{}, error) {
+	result := map[string]interface{}{
+		"id":               snapshot.Id,
+		"name":             snapshot.Name,
+		"description":      snapshot.Description,
+		"creation_date":    snapshot.CreationDate.String(),
+		"last_change_date": snapshot.LastChangeDate.String(),
+		"is_current":       snapshot.IsCurrent,
+		"is_system":        snapshot.IsSystem,
+		// zero values keep filters working for snapshots without references
+		"instance_id":        int32(0),
+		"creation_user_id":   int32(0),
+		"parent_snapshot_id": int32(0),
+	}
+	if snapshot.Instance != nil {
+		result["instance_id"] = snapshot.Instance.Id
+	}
+	if snapshot.CreationUser != nil {
+		result["creation_user_id"] = snapshot.CreationUser.Id
+	}
+	if snapshot.SnapshotParent != nil {
+		result["parent_snapshot_id"] = snapshot.SnapshotParent.Id
+	}
+	return result, nil
+}
@@ -0,0 +1,39 @@
+package oktawave
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestOktawave_DataSource_InstanceSnapshots(t *testing.T) {
+	resourcesConfig := testAccInstanceSnapshotConfig("test-snapshot", "")
+
+	dataSourceConfig := `
+data "oktawave_instance_snapshots" "snapshots" {
+	filter {
+		key = "instance_id"
+		values = [oktawave_instance.test-instance.id]
+	}
+}
+	`
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceSnapshotDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: resourcesConfig,
+			},
+			{
+				Config: resourcesConfig + dataSourceConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.oktawave_instance_snapshots.snapshots", "items.#", "1"),
+					resource.TestCheckResourceAttr("data.oktawave_instance_snapshots.snapshots", "items.0.name", "test-snapshot"),
+					resource.TestCheckResourceAttrPair("data.oktawave_instance_snapshots.snapshots", "items.0.id", "oktawave_instance_snapshot.test-snapshot", "id"),
+				),
+			},
+		},
+	})
+}
@@ -64,7 +64,7 @@ func testAccCheckInstanceDatasourceDestroy(s *terraform.State) error {
 
 	errors := []interface{}{}
 	for _, rs := range s.RootModule().Resources {
-		if rs.Type != "oktawave_instance" {
+		if rs.Type != "oktawave_instance" && rs.Type != "oktawave_instance_clone" {
 			continue
 		}
 
@@ -0,0 +1,27 @@
+package oktawave
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOktawaveDiskAttachment_importBasic(t *testing.T) {
+	resourceName := "oktawave_disk_attachment.test-attachment"
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDiskDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDiskAttachmentConfig,
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package oktawave
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccOktawaveHealthCheck_importBasic(t *testing.T) {
+	resourceName := "oktawave_health_check.test-health-check"
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckHealthCheckDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccHealthCheckConfig("test-health-check", 60),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateIdFunc: func(s *terraform.State) (string, error) {
+					rs, ok := s.RootModule().Resources[resourceName]
+					if !ok {
+						return "", fmt.Errorf("Not found: %s", resourceName)
+					}
+					return "http/" + rs.Primary.ID, nil
+				},
+			},
+		},
+	})
+}
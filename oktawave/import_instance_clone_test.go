@@ -0,0 +1,73 @@
+package oktawave
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccOktawaveInstanceClone_importBasic(t *testing.T) {
+	resourceName := "oktawave_instance_clone.test-clone"
+	cloneConfig := `
+resource "oktawave_instance" "test-source" {
+	name = "test-clone-import-source"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+}
+
+resource "oktawave_instance_clone" "test-clone" {
+	source_instance_id = oktawave_instance.test-source.id
+	clone_type_id = 1
+	name = "test-clone-import"
+}`
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: cloneConfig,
+			},
+			{
+				ResourceName:            resourceName,
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"authorization_method_id"},
+				ImportStateIdFunc: func(s *terraform.State) (string, error) {
+					rs, ok := s.RootModule().Resources[resourceName]
+					if !ok {
+						return "", fmt.Errorf("Not found: %s", resourceName)
+					}
+					return fmt.Sprintf("%s/%s/%s", rs.Primary.ID, rs.Primary.Attributes["source_instance_id"], rs.Primary.Attributes["clone_type_id"]), nil
+				},
+			},
+		},
+	})
+}
+
+func TestResourceInstanceCloneImportId(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceInstanceClone().Schema, map[string]interface{}{})
+	d.SetId("11/5/1/21,22")
+	if _, err := resourceInstanceCloneImport(context.Background(), d, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if d.Id() != "11" || d.Get("source_instance_id").(int) != 5 || d.Get("clone_type_id").(int) != 1 || d.Get("cloned_disks_ids").(*schema.Set).Len() != 2 {
+		t.Errorf("expected clone 11 of instance 5 with type 1 and 2 cloned disks, got %v %v %v %v",
+			d.Id(), d.Get("source_instance_id"), d.Get("clone_type_id"), d.Get("cloned_disks_ids").(*schema.Set).List())
+	}
+
+	for _, id := range []string{"11", "11/5", "11/x/1", "11/5/1/a"} {
+		d := schema.TestResourceDataRaw(t, resourceInstanceClone().Schema, map[string]interface{}{})
+		d.SetId(id)
+		if _, err := resourceInstanceCloneImport(context.Background(), d, nil); err == nil {
+			t.Errorf("expected import id %q to be rejected", id)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package oktawave
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOktawaveInstancePool_importBasic(t *testing.T) {
+	resourceName := "oktawave_instance_pool.test-pool"
+	poolConfig := `
+resource "oktawave_opn" "test-opn" {
+	name = "test-pool-opn"
+}
+
+resource "oktawave_instance_pool" "test-pool" {
+	name_prefix = "test-import-pool"
+	instances_count = 2
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	without_public_ip = true
+	opn_ids = [oktawave_opn.test-opn.id]
+}`
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: poolConfig,
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
@@ -0,0 +1,28 @@
+package oktawave
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOktawaveInstanceSnapshot_importBasic(t *testing.T) {
+	resourceName := "oktawave_instance_snapshot.test-snapshot"
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceSnapshotDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccInstanceSnapshotConfig("test-snapshot", ""),
+			},
+			{
+				ResourceName:            resourceName,
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"restore_trigger"},
+			},
+		},
+	})
+}
@@ -0,0 +1,27 @@
+package oktawave
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOktawaveIpAttachment_importBasic(t *testing.T) {
+	resourceName := "oktawave_ip_attachment.test-attachment"
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccIpAttachmentConfig("test-instance1"),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
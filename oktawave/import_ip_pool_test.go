@@ -0,0 +1,27 @@
+package oktawave
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOktawaveIpPool_importBasic(t *testing.T) {
+	resourceName := "oktawave_ip_pool.test-pool"
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckIpPoolDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccIpPoolConfig(2),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
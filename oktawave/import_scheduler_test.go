@@ -0,0 +1,27 @@
+package oktawave
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOktawaveScheduler_importBasic(t *testing.T) {
+	resourceName := "oktawave_scheduler.test-scheduler"
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckSchedulerDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccSchedulerConfig("test-scheduler", "2030-01-01T18:00:00Z"),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -49,41 +50,57 @@ func Provider() *schema.Provider {
 				Optional:    true,
 				DefaultFunc: envBoolDefaultFunc("OKTAWAVE_OKS_API_SKIP_TLS", false),
 			},
+			"dns_resolver": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("OKTAWAVE_DNS_RESOLVER", nil),
+			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"oktawave_instance":      resourceInstance(),
-			"oktawave_template":      resourceTemplate(),
-			"oktawave_disk":          resourceDisk(),
-			"oktawave_opn":           resourceOpn(),
-			"oktawave_ip":            resourceIpAddress(),
-			"oktawave_group":         resourceGroup(),
-			"oktawave_load_balancer": resourceLoadBalancer(),
-			"oktawave_ssh_key":       resourceSshKey(),
-			"oktawave_oks_cluster":   resourceOksCluster(),
-			"oktawave_oks_node":      resourceOksNode(),
+			"oktawave_instance":          resourceInstance(),
+			"oktawave_instance_pool":     resourceInstancePool(),
+			"oktawave_instance_clone":    resourceInstanceClone(),
+			"oktawave_instance_snapshot": resourceInstanceSnapshot(),
+			"oktawave_scheduler":         resourceScheduler(),
+			"oktawave_health_check":      resourceHealthCheck(),
+			"oktawave_template":          resourceTemplate(),
+			"oktawave_disk":              resourceDisk(),
+			"oktawave_disk_attachment":   resourceDiskAttachment(),
+			"oktawave_opn":               resourceOpn(),
+			"oktawave_ip":                resourceIpAddress(),
+			"oktawave_ip_attachment":     resourceIpAttachment(),
+			"oktawave_ip_pool":           resourceIpPool(),
+			"oktawave_group":             resourceGroup(),
+			"oktawave_load_balancer":     resourceLoadBalancer(),
+			"oktawave_ssh_key":           resourceSshKey(),
+			"oktawave_oks_cluster":       resourceOksCluster(),
+			"oktawave_oks_node":          resourceOksNode(),
 		},
 		DataSourcesMap: map[string]*schema.Resource{
-			"oktawave_instance":       dataSourceInstance(),
-			"oktawave_instances":      dataSourceInstances(),
-			"oktawave_template":       dataSourceTemplate(),
-			"oktawave_templates":      dataSourceTemplates(),
-			"oktawave_disk":           dataSourceDisk(),
-			"oktawave_disks":          dataSourceDisks(),
-			"oktawave_opn":            dataSourceOpn(),
-			"oktawave_opns":           dataSourceOpns(),
-			"oktawave_ip":             dataSourceIp(),
-			"oktawave_ips":            dataSourceIps(),
-			"oktawave_group":          dataSourceGroup(),
-			"oktawave_groups":         dataSourceGroups(),
-			"oktawave_load_balancer":  dataSourceLoadBalancer(),
-			"oktawave_load_balancers": dataSourceLoadBalancers(),
-			"oktawave_ssh_key":        dataSourceSshKey(),
-			"oktawave_ssh_keys":       dataSourceSshKeys(),
-			"oktawave_oks_cluster":    dataSourceOksCluster(),
-			"oktawave_oks_clusters":   dataSourceOksClusters(),
-			"oktawave_oks_node":       dataSourceOksNode(),
-			"oktawave_subregions":     dataSourceSubregions(),
-			"oktawave_instance_types": dataSourceInstanceTypes(),
+			"oktawave_instance":           dataSourceInstance(),
+			"oktawave_instances":          dataSourceInstances(),
+			"oktawave_instance_snapshots": dataSourceInstanceSnapshots(),
+			"oktawave_health_checks":      dataSourceHealthChecks(),
+			"oktawave_template":           dataSourceTemplate(),
+			"oktawave_templates":          dataSourceTemplates(),
+			"oktawave_disk":               dataSourceDisk(),
+			"oktawave_disks":              dataSourceDisks(),
+			"oktawave_opn":                dataSourceOpn(),
+			"oktawave_opns":               dataSourceOpns(),
+			"oktawave_ip":                 dataSourceIp(),
+			"oktawave_ips":                dataSourceIps(),
+			"oktawave_group":              dataSourceGroup(),
+			"oktawave_groups":             dataSourceGroups(),
+			"oktawave_load_balancer":      dataSourceLoadBalancer(),
+			"oktawave_load_balancers":     dataSourceLoadBalancers(),
+			"oktawave_ssh_key":            dataSourceSshKey(),
+			"oktawave_ssh_keys":           dataSourceSshKeys(),
+			"oktawave_oks_cluster":        dataSourceOksCluster(),
+			"oktawave_oks_clusters":       dataSourceOksClusters(),
+			"oktawave_oks_node":           dataSourceOksNode(),
+			"oktawave_subregions":         dataSourceSubregions(),
+			"oktawave_instance_types":     dataSourceInstanceTypes(),
+			"oktawave_cloudinit_config":   dataSourceCloudInitConfig(),
 		},
 		ConfigureContextFunc: providerConfigure,
 	}
@@ -153,6 +170,17 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}
 		"OKS_url": oksCfg.BasePath,
 	})
 
+	var resolver hostResolver = net.DefaultResolver
+	if dnsResolver, ok := d.GetOk("dns_resolver"); ok && dnsResolver != "" {
+		tflog.Info(ctx, fmt.Sprintf("DNS resolver was set to \"%v\"", dnsResolver))
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, dnsResolver.(string))
+			},
+		}
+	}
+
 	odkClient := odk.NewAPIClient(odkCfg)
 	oksClient := oks.NewAPIClient(oksCfg)
 
@@ -161,6 +189,8 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}
 		odkClient: *odkClient,
 		oksAuth:   &oksAuth,
 		oksClient: *oksClient,
+		ipCache:   &ipListCache{},
+		resolver:  resolver,
 	}
 	tflog.Debug(ctx, "Oktawave provider initialized")
 	return &client, *new(diag.Diagnostics)
@@ -1,9 +1,14 @@
 package oktawave
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/oktawave-code/odk"
 )
 
 func TestProvider(t *testing.T) {
@@ -25,3 +30,25 @@ func init() {
 
 func testAccPreCheck(t *testing.T) {
 }
+
+// testOdkMockConfig returns client configuration talking to local mock of ODK API.
+func testOdkMockConfig(t *testing.T, handler http.Handler) *ClientConfig {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	odkCfg := odk.NewConfiguration()
+	odkCfg.BasePath = server.URL
+	odkAuth := context.WithValue(context.Background(), odk.ContextAccessToken, "test-token")
+	return &ClientConfig{
+		odkAuth:   &odkAuth,
+		odkClient: *odk.NewAPIClient(odkCfg),
+		ipCache:   &ipListCache{},
+	}
+}
+
+func testWriteJson(t *testing.T, w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		t.Errorf("Can't encode mock response. %s", err)
+	}
+}
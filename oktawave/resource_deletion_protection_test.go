@@ -0,0 +1,42 @@
+package oktawave
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestDeletionProtectionBlocksDelete(t *testing.T) {
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	config.oksAuth = config.odkAuth
+
+	resources := map[string]*schema.Resource{
+		"oktawave_instance":       resourceInstance(),
+		"oktawave_instance_clone": resourceInstanceClone(),
+		"oktawave_disk":           resourceDisk(),
+		"oktawave_ip":             resourceIpAddress(),
+		"oktawave_ip_pool":        resourceIpPool(),
+		"oktawave_opn":            resourceOpn(),
+		"oktawave_oks_cluster":    resourceOksCluster(),
+		"oktawave_template":       resourceTemplate(),
+	}
+	for name, r := range resources {
+		d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+			"deletion_protection": true,
+		})
+		d.SetId("5")
+
+		diags := r.DeleteContext(context.Background(), d, config)
+		if !diags.HasError() {
+			t.Errorf("%s: expected delete to be refused", name)
+		}
+		if d.Id() != "5" {
+			t.Errorf("%s: expected resource to stay in state, id is %q", name, d.Id())
+		}
+	}
+}
@@ -10,17 +10,25 @@ import (
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/oktawave-code/odk"
 )
 
+const (
+	DISK_DETACH_POLICY_FAIL              = "fail"
+	DISK_DETACH_POLICY_DETACH            = "detach"
+	DISK_DETACH_POLICY_DETACH_AFTER_STOP = "detach_after_stop"
+)
+
 func resourceDisk() *schema.Resource {
 	return &schema.Resource{
 		CreateContext: resourceDiskCreate,
 		ReadContext:   resourceDiskRead,
 		UpdateContext: resourceDiskUpdate,
 		DeleteContext: resourceDiskDelete,
+		CustomizeDiff: resourceDiskCustomizeDiff,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: resourceDiskImport,
 		},
 		Schema: map[string]*schema.Schema{
 			// Required
@@ -32,7 +40,7 @@ func resourceDisk() *schema.Resource {
 			"tier_id": {
 				Type:        schema.TypeInt,
 				Required:    true,
-				Description: "Defines disk performance class. Value from dictionary #17",
+				Description: "Defines disk performance class. Value from dictionary #17. Tier change moves disk data and may take a long time.",
 			},
 			"subregion_id": {
 				Type:        schema.TypeInt,
@@ -46,18 +54,34 @@ func resourceDisk() *schema.Resource {
 				Default:     5,
 				Description: "Disk size in GB. At least 5 GB. Disk capacity can be only scaled up.",
 			},
+			"allow_replace_on_shrink": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "When true, decreasing capacity replaces disk with a new, empty one instead of failing at plan.",
+			},
 			"shared_disk_type_id": {
-				Type:       schema.TypeInt,
-				Optional:   true,
-				Deprecated: "Type of disk sharing. Value from dictionary #162",
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IntInSlice([]int{DICT_SHARE_TYPE_LINUX, DICT_SHARE_TYPE_WINDOWS}),
+				Description:  "Type of disk sharing. Value from dictionary #162: 1411 for Linux instances, 1412 for Windows instances. Only shared disk can be attached to more than one instance.",
 			},
 			"instance_ids": {
 				Type:     schema.TypeSet,
+				Optional: true,
 				Computed: true,
 				Elem: &schema.Schema{
 					Type: schema.TypeInt,
 				},
-				Description: "List of instances ids that are connected to this disk.",
+				Description: "List of instances ids that are connected to this disk. Can be set only for shared disks, operating system of every instance must match shared_disk_type_id.",
+			},
+			"deletion_protection": deletionProtectionSchema(),
+			"detach_policy": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      DISK_DETACH_POLICY_FAIL,
+				ValidateFunc: validation.StringInSlice([]string{DISK_DETACH_POLICY_FAIL, DISK_DETACH_POLICY_DETACH, DISK_DETACH_POLICY_DETACH_AFTER_STOP}, false),
+				Description:  "What to do when attached disk is destroyed. \"fail\" refuses to destroy it, \"detach\" detaches it from running instances, \"detach_after_stop\" shuts instances down for detaching and powers them on again.",
 			},
 			// Computed
 			"creation_user_id": {
@@ -71,19 +95,19 @@ func resourceDisk() *schema.Resource {
 				Description: "Date of disk creation.",
 			},
 			"is_shared": {
-				Type:       schema.TypeBool,
-				Computed:   true,
-				Deprecated: "Allows disk to be shared amongst multiple instances.",
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Allows disk to be shared amongst multiple instances.",
 			},
 			"is_locked": {
 				Type:        schema.TypeBool,
 				Computed:    true,
-				Description: "Tells if disk is currently locked.",
+				Description: "Tells if disk is currently locked by running operation. Oktawave API doesn't allow to lock disk on demand.",
 			},
 			"locking_date": {
 				Type:        schema.TypeString,
 				Computed:    true,
-				Description: "Disk lock date.",
+				Description: "Date when running operation locked disk.",
 			},
 			"is_freemium": {
 				Type:       schema.TypeBool,
@@ -94,7 +118,7 @@ func resourceDisk() *schema.Resource {
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(45 * time.Minute),
 		},
-		Description: "Oktawave Volume Storage(OVS) service provides block storage disks.",
+		Description: "Oktawave Volume Storage(OVS) service provides block storage disks. Oktawave API doesn't support snapshots or clones of OVS disks, new disk is always empty.",
 	}
 }
 
@@ -111,22 +135,20 @@ func resourceDiskCreate(ctx context.Context, d *schema.ResourceData, m interface
 		SubregionId:   int32(d.Get("subregion_id").(int)),
 	}
 
-	tflog.Debug(ctx, "calling OVSApi.DisksPost")
-	ticket, _, err := client.OVSApi.DisksPost(*auth, createCommand)
-	if err != nil {
-		return diag.Errorf("ODK Error in OVSApi.DisksPost. %s", err)
+	if shareTypeId, ok := d.GetOk("shared_disk_type_id"); ok {
+		createCommand.SharedDiskTypeId = int32(shareTypeId.(int))
+		createCommand.InstanceIdsList = castToInt32(d.Get("instance_ids").(*schema.Set).List())
+		if err := checkSharedDiskInstances(ctx, client, auth, createCommand.SharedDiskTypeId, createCommand.InstanceIdsList); err != nil {
+			return diag.FromErr(err)
+		}
 	}
 
-	createTicket, err := waitForTicket(client, auth, ticket)
+	diskId, err := createDisk(ctx, client, auth, createCommand)
 	if err != nil {
-		return diag.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
-	}
-	if createTicket.Status.Id != DICT_TICKET_SUCCEED {
-		return diag.Errorf("Unable to create OVS. Ticket status=%v", createTicket.Status.Id)
+		return diag.FromErr(err)
 	}
 
-	tflog.Info(ctx, fmt.Sprintf("successfully created OVS. id=%v", createTicket.ObjectId))
-	d.SetId(strconv.Itoa(int(createTicket.ObjectId)))
+	d.SetId(strconv.Itoa(int(diskId)))
 
 	return resourceDiskRead(ctx, d, m)
 }
@@ -170,34 +192,65 @@ func resourceDiskUpdate(ctx context.Context, d *schema.ResourceData, m interface
 		DiskName:      d.Get("name").(string),
 		SpaceCapacity: int32(d.Get("capacity").(int)),
 		TierId:        int32(d.Get("tier_id").(int)),
+		SubregionId:   int32(d.Get("subregion_id").(int)),
 	}
+	needsUpdate := d.HasChanges("name", "capacity", "tier_id", "subregion_id")
 
-	if d.HasChange("subregion_id") {
-		tflog.Info(ctx, "subregion id change detected")
-		oldSubregion, newSubregion := d.GetChange("subregion_id")
-		// detach from instances in old subregion
-		updateDiskCmd.SubregionId = int32(oldSubregion.(int))
-		if err := updateDisk(ctx, client, auth, int32(diskId), updateDiskCmd); err != nil {
-			return err
+	shareTypeId := int32(d.Get("shared_disk_type_id").(int))
+	if shareTypeId != 0 && d.HasChange("instance_ids") {
+		tflog.Info(ctx, "shared disk instances change detected")
+		oldInstances, newInstances := d.GetChange("instance_ids")
+		updateDiskCmd.InstanceIdsList = castToInt32(newInstances.(*schema.Set).List())
+		if err := checkSharedDiskInstances(ctx, client, auth, shareTypeId, updateDiskCmd.InstanceIdsList); err != nil {
+			return diag.FromErr(err)
+		}
+		// empty list is omitted in request, so last instances have to be detached one by one
+		if len(updateDiskCmd.InstanceIdsList) == 0 {
+			for _, instanceId := range castToInt32(oldInstances.(*schema.Set).List()) {
+				tflog.Debug(ctx, "calling ODK OVSApi.DisksDetachFromInstance", map[string]interface{}{"diskId": diskId, "instanceId": instanceId})
+				if err := detachDiskFromInstance(client, auth, int32(diskId), instanceId); err != nil {
+					return diag.Errorf("Detaching shared disk %v from instance %v failed. %s", diskId, instanceId, err)
+				}
+			}
+		} else {
+			needsUpdate = true
+		}
+	}
+
+	if needsUpdate {
+		// request without instances list detaches disk from all instances, so current connections are sent back
+		if updateDiskCmd.InstanceIdsList == nil {
+			tflog.Debug(ctx, "calling ODK OVSApi.DisksGet", map[string]interface{}{"id": diskId})
+			disk, _, err := client.OVSApi.DisksGet(*auth, int32(diskId), nil)
+			if err != nil {
+				return diag.Errorf("ODK Error in OVSApi.DisksGet. %s", err)
+			}
+			updateDiskCmd.InstanceIdsList = castIntToInt32(getConnectionInstanceIds(disk.Connections))
 		}
-		// change subregion
-		updateDiskCmd.SubregionId = int32(newSubregion.(int))
 		if err := updateDisk(ctx, client, auth, int32(diskId), updateDiskCmd); err != nil {
 			return err
 		}
 	}
 
-	updateDiskCmd.SubregionId = int32(d.Get("subregion_id").(int))
-	if err := updateDisk(ctx, client, auth, int32(diskId), updateDiskCmd); err != nil {
-		return err
+	var diags diag.Diagnostics
+	if d.HasChange("tier_id") {
+		oldTier, newTier := d.GetChange("tier_id")
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Tier of OVS %v was changed from %v to %v", diskId, oldTier, newTier),
+			Detail:   "Tier change moves disk data and may take a long time.",
+		})
 	}
-
-	return resourceDiskRead(ctx, d, m)
+	return append(diags, resourceDiskRead(ctx, d, m)...)
 }
 
 func resourceDiskDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	tflog.Info(ctx, "deleting disk")
 
+	if diags := checkDeletionProtection(d); diags != nil {
+		return diags
+	}
+
 	client := m.(*ClientConfig).odkClient
 	auth := m.(*ClientConfig).odkAuth
 
@@ -206,42 +259,186 @@ func resourceDiskDelete(ctx context.Context, d *schema.ResourceData, m interface
 		return diag.Errorf("Invalid OVS id: %v %s", d.Id(), err)
 	}
 
-	detachCommand := odk.UpdateDiskCommand{
-		DiskName:        d.Get("name").(string),
-		SpaceCapacity:   int32(d.Get("capacity").(int)),
-		TierId:          int32(d.Get("tier_id").(int)),
-		SubregionId:     int32(d.Get("subregion_id").(int)),
-		InstanceIdsList: nil,
+	// state may be outdated, check current connections
+	tflog.Debug(ctx, "calling ODK OVSApi.DisksGet", map[string]interface{}{"id": diskId})
+	disk, resp, err := client.OVSApi.DisksGet(*auth, int32(diskId), nil)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			tflog.Warn(ctx, fmt.Sprintf("OVS %v no longer exists", diskId))
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("ODK Error in OVSApi.DisksGet. %s", err)
 	}
-	tflog.Debug(ctx, "calling ODK OVSApi.DisksPut")
-	ticket, _, err := client.OVSApi.DisksPut(*auth, int32(diskId), detachCommand)
+	instanceIds := castIntToInt32(getConnectionInstanceIds(disk.Connections))
+
+	if len(instanceIds) > 0 {
+		switch d.Get("detach_policy").(string) {
+		case DISK_DETACH_POLICY_DETACH:
+			for _, instanceId := range instanceIds {
+				tflog.Debug(ctx, "calling ODK OVSApi.DisksDetachFromInstance", map[string]interface{}{"diskId": diskId, "instanceId": instanceId})
+				if err := detachDiskFromInstance(client, auth, int32(diskId), instanceId); err != nil {
+					return diag.Errorf("Detaching OVS %v from instance %v failed. %s", diskId, instanceId, err)
+				}
+			}
+		case DISK_DETACH_POLICY_DETACH_AFTER_STOP:
+			for _, instanceId := range instanceIds {
+				if err := detachDiskFromStoppedInstance(ctx, client, auth, int32(diskId), instanceId); err != nil {
+					return diag.FromErr(err)
+				}
+			}
+		default:
+			return diag.Errorf("OVS %v is attached to instances %v. Detach it first, or set detach_policy to %q or %q", diskId, instanceIds, DISK_DETACH_POLICY_DETACH, DISK_DETACH_POLICY_DETACH_AFTER_STOP)
+		}
+	}
+
+	if err := deleteDisk(ctx, client, auth, int32(diskId)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// resourceDiskImport stores defaults of arguments which can't be read from API.
+func resourceDiskImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	if d.Set("detach_policy", DISK_DETACH_POLICY_FAIL) != nil {
+		return nil, fmt.Errorf("can't set detach policy")
+	}
+	return importStatePassthroughWithDeletionProtection(ctx, d, m)
+}
+
+func resourceDiskCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Id() != "" && d.HasChange("capacity") {
+		oldCapacity, newCapacity := d.GetChange("capacity")
+		if newCapacity.(int) < oldCapacity.(int) {
+			if !d.Get("allow_replace_on_shrink").(bool) {
+				return fmt.Errorf("disk can be only scaled up, can't shrink it from %d GB to %d GB. Set allow_replace_on_shrink to replace it with a new disk", oldCapacity.(int), newCapacity.(int))
+			}
+			if err := d.ForceNew("capacity"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if d.Id() != "" && d.HasChange("subregion_id") {
+		oldInstances, _ := d.GetChange("instance_ids")
+		if oldInstances.(*schema.Set).Len() > 0 {
+			return fmt.Errorf("subregion of disk %s can't be changed while it's attached to instances %v. Detach it first", d.Id(), oldInstances.(*schema.Set).List())
+		}
+	}
+
+	// instance_ids is also computed, so only value from configuration is checked
+	if !d.GetRawConfig().GetAttr("instance_ids").IsNull() && d.NewValueKnown("shared_disk_type_id") && d.Get("shared_disk_type_id").(int) == 0 {
+		return fmt.Errorf("instance_ids can be set only for shared disks. Set shared_disk_type_id, or attach disk with oktawave_disk_attachment or disks_ids of oktawave_instance")
+	}
+	return nil
+}
+
+// checkSharedDiskInstances verifies that operating system of every instance matches disk share type.
+func checkSharedDiskInstances(ctx context.Context, client odk.APIClient, auth *context.Context, shareTypeId int32, instanceIds []int32) error {
+	for _, instanceId := range instanceIds {
+		tflog.Debug(ctx, "calling ODK OCIApi.InstancesGet_2", map[string]interface{}{"id": instanceId})
+		instance, _, err := client.OCIApi.InstancesGet_2(*auth, instanceId, nil)
+		if err != nil {
+			return fmt.Errorf("ODK Error in OCIApi.InstancesGet_2. %s", err)
+		}
+		if instance.SystemCategory == nil {
+			return fmt.Errorf("can't check operating system of instance %v", instanceId)
+		}
+		var wantedCategoryId int32 = DICT_SYSTEM_CATEGORY_LINUX
+		if shareTypeId == DICT_SHARE_TYPE_WINDOWS {
+			wantedCategoryId = DICT_SYSTEM_CATEGORY_WINDOWS
+		}
+		if instance.SystemCategory.Id != DICT_SYSTEM_CATEGORY_LINUX && instance.SystemCategory.Id != DICT_SYSTEM_CATEGORY_WINDOWS {
+			return fmt.Errorf("can't check operating system of instance %v, unknown system category %v", instanceId, instance.SystemCategory.Id)
+		}
+		if instance.SystemCategory.Id != wantedCategoryId {
+			return fmt.Errorf("instance %v runs %s, it can't use disk with share type %v", instanceId, instance.SystemCategory.Label, shareTypeId)
+		}
+	}
+	return nil
+}
+
+// detachDiskFromStoppedInstance shuts instance down for detaching disk. Instance is powered on again if it was running.
+func detachDiskFromStoppedInstance(ctx context.Context, client odk.APIClient, auth *context.Context, diskId int32, instanceId int32) error {
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesGet_2", map[string]interface{}{"id": instanceId})
+	instance, _, err := client.OCIApi.InstancesGet_2(*auth, instanceId, nil)
 	if err != nil {
-		return diag.Errorf("ODK Error in OVSApi.DisksPut. %s", err)
+		return fmt.Errorf("ODK Error in OCIApi.InstancesGet_2. %s", err)
 	}
+	wasRunning := instance.Status != nil && instance.Status.Id == DICT_INSTANCE_STATUS_ON
 
-	ticket, err = waitForTicket(client, auth, ticket)
+	if wasRunning {
+		tflog.Debug(ctx, "calling ODK OCIApi.InstancesShutdown", map[string]interface{}{"id": instanceId})
+		ticket, _, err := client.OCIApi.InstancesShutdown(*auth, instanceId)
+		if err != nil {
+			return fmt.Errorf("ODK Error in OCIApi.InstancesShutdown. %s", err)
+		}
+		ticket, err = waitForTicket(client, auth, ticket)
+		if err != nil {
+			return fmt.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+		}
+		if ticket.Status.Id != DICT_TICKET_SUCCEED {
+			return fmt.Errorf("unable to shut down instance %v. Ticket status=%v", instanceId, ticket.Status.Id)
+		}
+	}
+
+	tflog.Debug(ctx, "calling ODK OVSApi.DisksDetachFromInstance", map[string]interface{}{"diskId": diskId, "instanceId": instanceId})
+	if err := detachDiskFromInstance(client, auth, diskId, instanceId); err != nil {
+		return fmt.Errorf("detaching OVS %v from instance %v failed. %s", diskId, instanceId, err)
+	}
+
+	if wasRunning {
+		tflog.Debug(ctx, "calling ODK OCIApi.InstancesPowerOn", map[string]interface{}{"id": instanceId})
+		ticket, _, err := client.OCIApi.InstancesPowerOn(*auth, instanceId)
+		if err != nil {
+			return fmt.Errorf("ODK Error in OCIApi.InstancesPowerOn. %s", err)
+		}
+		ticket, err = waitForTicket(client, auth, ticket)
+		if err != nil {
+			return fmt.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+		}
+		if ticket.Status.Id != DICT_TICKET_SUCCEED {
+			return fmt.Errorf("unable to power on instance %v. Ticket status=%v", instanceId, ticket.Status.Id)
+		}
+	}
+	return nil
+}
+
+func createDisk(ctx context.Context, client odk.APIClient, auth *context.Context, createCommand odk.CreateDiskCommand) (int32, error) {
+	tflog.Debug(ctx, "calling OVSApi.DisksPost")
+	ticket, _, err := client.OVSApi.DisksPost(*auth, createCommand)
 	if err != nil {
-		return diag.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+		return 0, fmt.Errorf("ODK Error in OVSApi.DisksPost. %s", err)
 	}
-	if ticket.Status.Id != DICT_TICKET_SUCCEED {
-		return diag.Errorf("Unable to detach OVS. Ticket status=%v", ticket.Status.Id)
+
+	createTicket, err := waitForTicket(client, auth, ticket)
+	if err != nil {
+		return 0, fmt.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
 	}
+	if createTicket.Status.Id != DICT_TICKET_SUCCEED {
+		return 0, fmt.Errorf("unable to create OVS. Ticket status=%v", createTicket.Status.Id)
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("successfully created OVS. id=%v", createTicket.ObjectId))
+	return createTicket.ObjectId, nil
+}
 
-	tflog.Debug(ctx, "calling ODK OVSApi.DisksDelete")
-	ticket, _, err = client.OVSApi.DisksDelete(*auth, int32(diskId))
+func deleteDisk(ctx context.Context, client odk.APIClient, auth *context.Context, diskId int32) error {
+	tflog.Debug(ctx, "calling ODK OVSApi.DisksDelete", map[string]interface{}{"id": diskId})
+	ticket, _, err := client.OVSApi.DisksDelete(*auth, diskId)
 	if err != nil {
-		return diag.Errorf("ODK Error in OVSApi.DisksDelete. %s", err)
+		return fmt.Errorf("ODK Error in OVSApi.DisksDelete. %s", err)
 	}
 
 	ticket, err = waitForTicket(client, auth, ticket)
 	if err != nil {
-		return diag.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+		return fmt.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
 	}
 	if ticket.Status.Id != DICT_TICKET_SUCCEED {
-		return diag.Errorf("Unable to delete OVS. Ticket status=%v", ticket.Status.Id)
+		return fmt.Errorf("unable to delete OVS %v. Ticket status=%v", diskId, ticket.Status.Id)
 	}
-
-	d.SetId("")
 	return nil
 }
 
@@ -280,10 +477,8 @@ func loadDiskData(ctx context.Context, d *schema.ResourceData, m interface{}, di
 	if d.Set("locking_date", disk.LockingDate.String()) != nil {
 		return diag.Errorf("Can't retrieve locking date")
 	}
-	if len(disk.Connections) > 0 {
-		if d.Set("instance_ids", getConnectionInstanceIds(disk.Connections)) != nil {
-			return diag.Errorf("Can't retrieve instances list")
-		}
+	if d.Set("instance_ids", getConnectionInstanceIds(disk.Connections)) != nil {
+		return diag.Errorf("Can't retrieve instances list")
 	}
 	if d.Set("is_freemium", disk.IsFreemium) != nil {
 		return diag.Errorf("Can't retrieve freemium state")
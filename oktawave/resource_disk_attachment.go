@@ -0,0 +1,156 @@
+package oktawave
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceDiskAttachment() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceDiskAttachmentCreate,
+		ReadContext:   resourceDiskAttachmentRead,
+		DeleteContext: resourceDiskAttachmentDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: map[string]*schema.Schema{
+			// Required
+			"disk_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Id of attached disk.",
+			},
+			"instance_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Id of instance disk is attached to. Changing it moves disk to another instance.",
+			},
+			// Computed
+			"controller": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Controller disk is connected to.",
+			},
+			"slot": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Controller slot disk is connected to.",
+			},
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(45 * time.Minute),
+		},
+		Description: "Attachment of disk to instance. Id has <disk_id>/<instance_id> format. Leave disks_ids of oktawave_instance unset, so both don't manage the same disks.",
+	}
+}
+
+func resourceDiskAttachmentCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "creating disk attachment")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	diskId := int32(d.Get("disk_id").(int))
+	instanceId := int32(d.Get("instance_id").(int))
+	tflog.Debug(ctx, "calling ODK OVSApi.DisksAttachToInstance", map[string]interface{}{"diskId": diskId, "instanceId": instanceId})
+	if err := attachDiskToInstance(client, auth, diskId, instanceId); err != nil {
+		return diag.Errorf("Attaching disk %v to instance %v failed. %s", diskId, instanceId, err)
+	}
+
+	d.SetId(fmt.Sprintf("%d/%d", diskId, instanceId))
+	return resourceDiskAttachmentRead(ctx, d, m)
+}
+
+func resourceDiskAttachmentRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "reading disk attachment")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	diskId, instanceId, err := parseDiskAttachmentId(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	tflog.Debug(ctx, "calling ODK OVSApi.DisksGet", map[string]interface{}{"id": diskId})
+	disk, resp, err := client.OVSApi.DisksGet(*auth, diskId, nil)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			tflog.Warn(ctx, fmt.Sprintf("Disk %v not found, removing attachment from state", diskId))
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("ODK Error in OVSApi.DisksGet. %s", err)
+	}
+
+	for _, connection := range disk.Connections {
+		if connection.Instance == nil || connection.Instance.Id != instanceId {
+			continue
+		}
+		// Store everything
+		tflog.Debug(ctx, "Parsing returned data")
+		if d.Set("disk_id", diskId) != nil {
+			return diag.Errorf("Can't retrieve disk id")
+		}
+		if d.Set("instance_id", instanceId) != nil {
+			return diag.Errorf("Can't retrieve instance id")
+		}
+		if d.Set("controller", connection.Controller) != nil {
+			return diag.Errorf("Can't retrieve controller")
+		}
+		if d.Set("slot", connection.Slot) != nil {
+			return diag.Errorf("Can't retrieve slot")
+		}
+		return nil
+	}
+
+	tflog.Warn(ctx, fmt.Sprintf("Disk %v is no longer attached to instance %v, removing attachment from state", diskId, instanceId))
+	d.SetId("")
+	return nil
+}
+
+func resourceDiskAttachmentDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "deleting disk attachment")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	diskId, instanceId, err := parseDiskAttachmentId(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	tflog.Debug(ctx, "calling ODK OVSApi.DisksDetachFromInstance", map[string]interface{}{"diskId": diskId, "instanceId": instanceId})
+	if err := detachDiskFromInstance(client, auth, diskId, instanceId); err != nil {
+		return diag.Errorf("Detaching disk %v from instance %v failed. %s", diskId, instanceId, err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+func parseDiskAttachmentId(id string) (int32, int32, error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid disk attachment id %q, expected <disk_id>/<instance_id>", id)
+	}
+	diskId, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid disk id in disk attachment id %q. %s", id, err)
+	}
+	instanceId, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid instance id in disk attachment id %q. %s", id, err)
+	}
+	return int32(diskId), int32(instanceId), nil
+}
@@ -0,0 +1,179 @@
+package oktawave
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/oktawave-code/odk"
+)
+
+const testAccDiskAttachmentConfig = `
+resource "oktawave_ip" "test-ip1" {
+	subregion_id = 1
+}
+
+resource "oktawave_instance" "test-instance1" {
+	depends_on = [oktawave_ip.test-ip1]
+	name = "test-instance1"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	public_ips = [oktawave_ip.test-ip1.id]
+}
+
+resource "oktawave_disk" "test-disk" {
+	name = "disk1"
+	tier_id = 48
+	subregion_id = 1
+	capacity = 5
+}
+
+resource "oktawave_disk_attachment" "test-attachment" {
+	disk_id = oktawave_disk.test-disk.id
+	instance_id = oktawave_instance.test-instance1.id
+}
+`
+
+func TestAccOktawaveDiskAttachment_Basic(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDiskDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDiskAttachmentConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair("oktawave_disk_attachment.test-attachment", "disk_id", "oktawave_disk.test-disk", "id"),
+					resource.TestCheckResourceAttrPair("oktawave_disk_attachment.test-attachment", "instance_id", "oktawave_instance.test-instance1", "id"),
+					resource.TestCheckResourceAttrSet("oktawave_disk_attachment.test-attachment", "controller"),
+					resource.TestCheckResourceAttrSet("oktawave_disk_attachment.test-attachment", "slot"),
+				),
+			},
+			{
+				// disk attached by oktawave_disk_attachment is not a drift of instance
+				Config:             testAccDiskAttachmentConfig,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: false,
+			},
+		},
+	})
+}
+
+func TestResourceInstanceDisksIdsDiff(t *testing.T) {
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	r := resourceInstance()
+	attributes := map[string]string{
+		"id":                   "5",
+		"name":                 "test-instance1",
+		"subregion_id":         "1",
+		"system_disk_class_id": "48",
+		"template_id":          "1021",
+		"type_id":              "1047",
+		"system_disk_size":     "5",
+		"disks_ids.#":          "1",
+		"disks_ids.7":          "7",
+	}
+	rawConfig := map[string]interface{}{
+		"name":                 "test-instance1",
+		"subregion_id":         1,
+		"system_disk_class_id": 48,
+		"template_id":          1021,
+		"type_id":              1047,
+	}
+
+	// disk attached by oktawave_disk_attachment, disks_ids not set in config
+	diff, err := r.SimpleDiff(context.Background(), testInstanceState(r, attributes, rawConfig), terraform.NewResourceConfigRaw(rawConfig), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != nil && len(diff.Attributes) > 0 {
+		for key := range diff.Attributes {
+			if strings.HasPrefix(key, "disks_ids") {
+				t.Errorf("expected no disks_ids diff when it's not configured, got %v", diff.Attributes[key])
+			}
+		}
+	}
+
+	// explicitly empty list detaches all disks
+	rawConfig["disks_ids"] = []interface{}{}
+	diff, err = r.SimpleDiff(context.Background(), testInstanceState(r, attributes, rawConfig), terraform.NewResourceConfigRaw(rawConfig), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == nil || diff.Attributes["disks_ids.#"] == nil || diff.Attributes["disks_ids.#"].New != "0" {
+		t.Errorf("expected empty disks_ids to detach all disks, got %v", diff)
+	}
+}
+
+// testInstanceState builds state with raw config, which is set by terraform during planning.
+func testInstanceState(r *schema.Resource, attributes map[string]string, rawConfig map[string]interface{}) *terraform.InstanceState {
+	configType := r.CoreConfigSchema().ImpliedType()
+	values := make(map[string]cty.Value)
+	for name, attributeType := range configType.AttributeTypes() {
+		values[name] = cty.NullVal(attributeType)
+	}
+	for name, value := range rawConfig {
+		switch v := value.(type) {
+		case string:
+			values[name] = cty.StringVal(v)
+		case int:
+			values[name] = cty.NumberIntVal(int64(v))
+		case []interface{}:
+			values[name] = cty.SetValEmpty(cty.Number)
+		}
+	}
+	return &terraform.InstanceState{
+		ID:         attributes["id"],
+		Attributes: attributes,
+		RawConfig:  cty.ObjectVal(values),
+	}
+}
+
+func TestResourceDiskAttachmentRead(t *testing.T) {
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/disks/7" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		testWriteJson(t, w, odk.Disk{
+			Id:          7,
+			Connections: []odk.DiskConnection{{Instance: &odk.BaseResource{Id: 5}, Controller: 1, Slot: 2}},
+		})
+	}))
+
+	d := schema.TestResourceDataRaw(t, resourceDiskAttachment().Schema, map[string]interface{}{})
+	d.SetId("7/5")
+	if diags := resourceDiskAttachmentRead(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	if d.Get("disk_id").(int) != 7 || d.Get("instance_id").(int) != 5 {
+		t.Errorf("expected ids parsed from composite id, got disk %v instance %v", d.Get("disk_id"), d.Get("instance_id"))
+	}
+	if d.Get("controller").(int) != 1 || d.Get("slot").(int) != 2 {
+		t.Errorf("expected controller 1 slot 2, got %v %v", d.Get("controller"), d.Get("slot"))
+	}
+
+	d.SetId("7/6")
+	if diags := resourceDiskAttachmentRead(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	if d.Id() != "" {
+		t.Errorf("expected detached disk to be removed from state, id is %q", d.Id())
+	}
+
+	d.SetId("7")
+	if diags := resourceDiskAttachmentRead(context.Background(), d, config); !diags.HasError() {
+		t.Errorf("expected malformed id to be rejected")
+	}
+}
@@ -1,11 +1,19 @@
 package oktawave
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/oktawave-code/odk"
 )
@@ -66,6 +74,381 @@ resource "oktawave_disk" "test-disk" {
 	})
 }
 
+func TestAccOktawaveDisk_Shrink(t *testing.T) {
+	diskConfig := `
+resource "oktawave_disk" "test-disk" {
+	name = "disk1"
+	tier_id = 48
+	subregion_id = 1
+	capacity = 6
+}
+`
+	shrinkConfig := `
+resource "oktawave_disk" "test-disk" {
+	name = "disk1"
+	tier_id = 48
+	subregion_id = 1
+	capacity = 5
+}
+`
+	replaceConfig := `
+resource "oktawave_disk" "test-disk" {
+	name = "disk1"
+	tier_id = 48
+	subregion_id = 1
+	capacity = 5
+	allow_replace_on_shrink = true
+}
+`
+	var disk, replacedDisk odk.Disk
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDiskDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: diskConfig,
+				Check:  testAccCheckDiskExists("oktawave_disk.test-disk", &disk),
+			},
+			{
+				Config:      shrinkConfig,
+				ExpectError: regexp.MustCompile("disk can be only scaled up"),
+			},
+			{
+				Config: replaceConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckDiskExists("oktawave_disk.test-disk", &replacedDisk),
+					resource.TestCheckResourceAttr("oktawave_disk.test-disk", "capacity", "5"),
+					func(s *terraform.State) error {
+						if disk.Id == replacedDisk.Id {
+							return fmt.Errorf("expected disk %v to be replaced", disk.Id)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestResourceDiskUpdateSendsOnlyNeededCommands(t *testing.T) {
+	puts := 0
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/disks/7":
+			testWriteJson(t, w, odk.Disk{
+				Id:            7,
+				Name:          "disk1",
+				SpaceCapacity: 5,
+				Tier:          &odk.DictionaryItem{Id: 48},
+				Subregion:     &odk.BaseResource{Id: 1},
+				CreationUser:  &odk.UserResource{Id: 1},
+			})
+		case r.Method == http.MethodPut && r.URL.Path == "/disks/7":
+			puts++
+			testWriteJson(t, w, odk.Ticket{Id: 1, EndDate: time.Now(), Status: &odk.DictionaryItem{Id: DICT_TICKET_SUCCEED}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	state := &terraform.InstanceState{
+		ID: "7",
+		Attributes: map[string]string{
+			"name":         "disk1",
+			"tier_id":      "48",
+			"subregion_id": "1",
+			"capacity":     "5",
+		},
+	}
+	d, err := schema.InternalMap(resourceDisk().Schema).Data(state, &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"deletion_protection": {Old: "", New: "true"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceDiskUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	if puts != 0 {
+		t.Errorf("expected no disk update for deletion_protection change, got %v", puts)
+	}
+
+	d, err = schema.InternalMap(resourceDisk().Schema).Data(state, &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"name":     {Old: "disk1", New: "disk1-updated"},
+			"capacity": {Old: "5", New: "6"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceDiskUpdate(context.Background(), d, config); diags.HasError() || len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics %v", diags)
+	}
+	if puts != 1 {
+		t.Errorf("expected single disk update for name and capacity change, got %v", puts)
+	}
+
+	d, err = schema.InternalMap(resourceDisk().Schema).Data(state, &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"tier_id": {Old: "48", New: "49"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := resourceDiskUpdate(context.Background(), d, config)
+	if diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	if len(diags) != 1 || diags[0].Severity != diag.Warning {
+		t.Errorf("expected tier change warning, got %v", diags)
+	}
+	if puts != 2 {
+		t.Errorf("expected disk update for tier change, got %v", puts)
+	}
+}
+
+func TestResourceDiskUpdateKeepsConnections(t *testing.T) {
+	var updateCmd odk.UpdateDiskCommand
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/disks/7":
+			testWriteJson(t, w, odk.Disk{
+				Id:            7,
+				Name:          "disk1",
+				SpaceCapacity: 6,
+				Tier:          &odk.DictionaryItem{Id: 48},
+				Subregion:     &odk.BaseResource{Id: 1},
+				CreationUser:  &odk.UserResource{Id: 1},
+				Connections:   []odk.DiskConnection{{Instance: &odk.BaseResource{Id: 5}}, {Instance: &odk.BaseResource{Id: 6}}},
+			})
+		case r.Method == http.MethodPut && r.URL.Path == "/disks/7":
+			if err := json.NewDecoder(r.Body).Decode(&updateCmd); err != nil {
+				t.Errorf("can't decode disk update. %s", err)
+			}
+			testWriteJson(t, w, odk.Ticket{Id: 1, EndDate: time.Now(), Status: &odk.DictionaryItem{Id: DICT_TICKET_SUCCEED}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	state := &terraform.InstanceState{
+		ID: "7",
+		Attributes: map[string]string{
+			"name":         "disk1",
+			"tier_id":      "48",
+			"subregion_id": "1",
+			"capacity":     "5",
+		},
+	}
+	d, err := schema.InternalMap(resourceDisk().Schema).Data(state, &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"capacity": {Old: "5", New: "6"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceDiskUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	if !reflect.DeepEqual(updateCmd.InstanceIdsList, []int32{5, 6}) {
+		t.Errorf("expected disk update to keep instances [5 6], got %v", updateCmd.InstanceIdsList)
+	}
+}
+
+func TestAccOktawaveDisk_Shared(t *testing.T) {
+	instancesConfig := `
+resource "oktawave_instance" "test-instance1" {
+	name = "test-instance1"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	lifecycle {
+		ignore_changes = [disks_ids]
+	}
+}
+
+resource "oktawave_instance" "test-instance2" {
+	name = "test-instance2"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	lifecycle {
+		ignore_changes = [disks_ids]
+	}
+}
+`
+	diskConfig := instancesConfig + `
+resource "oktawave_disk" "test-disk" {
+	name = "shared-disk1"
+	tier_id = 48
+	subregion_id = 1
+	capacity = 5
+	shared_disk_type_id = 1411
+	detach_policy = "detach"
+	instance_ids = [oktawave_instance.test-instance1.id, oktawave_instance.test-instance2.id]
+}
+`
+	updateConfig := instancesConfig + `
+resource "oktawave_disk" "test-disk" {
+	name = "shared-disk1"
+	tier_id = 48
+	subregion_id = 1
+	capacity = 5
+	shared_disk_type_id = 1411
+	detach_policy = "detach"
+	instance_ids = [oktawave_instance.test-instance2.id]
+}
+`
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDiskDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: diskConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_disk.test-disk", "shared_disk_type_id", "1411"),
+					resource.TestCheckResourceAttr("oktawave_disk.test-disk", "is_shared", "true"),
+					resource.TestCheckResourceAttr("oktawave_disk.test-disk", "instance_ids.#", "2"),
+				),
+			},
+			{
+				Config: updateConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_disk.test-disk", "instance_ids.#", "1"),
+					resource.TestCheckTypeSetElemAttrPair("oktawave_disk.test-disk", "instance_ids.*", "oktawave_instance.test-instance2", "id"),
+				),
+			},
+		},
+	})
+}
+
+func TestCheckSharedDiskInstances(t *testing.T) {
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		instance := testMockInstance(5)
+		instance.SystemCategory = &odk.DictionaryItem{Id: DICT_SYSTEM_CATEGORY_LINUX, Label: "Linux"}
+		switch r.URL.Path {
+		case "/instances/6":
+			instance = testMockInstance(6)
+			instance.SystemCategory = &odk.DictionaryItem{Id: DICT_SYSTEM_CATEGORY_WINDOWS, Label: "Windows Server"}
+		case "/instances/7":
+			// label is localized, so it must not decide about operating system
+			instance = testMockInstance(7)
+			instance.SystemCategory = &odk.DictionaryItem{Id: DICT_SYSTEM_CATEGORY_LINUX, Label: "Linux (bez Windows)"}
+		}
+		testWriteJson(t, w, instance)
+	}))
+
+	cases := []struct {
+		shareTypeId int32
+		instanceIds []int32
+		valid       bool
+	}{
+		{DICT_SHARE_TYPE_LINUX, []int32{5}, true},
+		{DICT_SHARE_TYPE_WINDOWS, []int32{6}, true},
+		{DICT_SHARE_TYPE_LINUX, []int32{5, 6}, false},
+		{DICT_SHARE_TYPE_WINDOWS, []int32{5}, false},
+		{DICT_SHARE_TYPE_LINUX, nil, true},
+		{DICT_SHARE_TYPE_LINUX, []int32{7}, true},
+		{DICT_SHARE_TYPE_WINDOWS, []int32{7}, false},
+	}
+	for _, c := range cases {
+		err := checkSharedDiskInstances(context.Background(), config.odkClient, config.odkAuth, c.shareTypeId, c.instanceIds)
+		if (err == nil) != c.valid {
+			t.Errorf("share type %v, instances %v: expected valid=%v, got error %v", c.shareTypeId, c.instanceIds, c.valid, err)
+		}
+	}
+}
+
+func TestResourceDiskDeleteDetachPolicy(t *testing.T) {
+	var requests []string
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		ticket := odk.Ticket{Id: 1, EndDate: time.Now(), Status: &odk.DictionaryItem{Id: DICT_TICKET_SUCCEED}}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/disks/7":
+			testWriteJson(t, w, odk.Disk{
+				Id:          7,
+				Connections: []odk.DiskConnection{{Instance: &odk.BaseResource{Id: 5}}},
+			})
+		case r.Method == http.MethodGet && r.URL.Path == "/instances/5":
+			instance := testMockInstance(5)
+			instance.Status = &odk.DictionaryItem{Id: DICT_INSTANCE_STATUS_ON}
+			testWriteJson(t, w, instance)
+		case r.Method == http.MethodPost || r.Method == http.MethodDelete:
+			testWriteJson(t, w, ticket)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	cases := map[string][]string{
+		DISK_DETACH_POLICY_FAIL: {
+			"GET /disks/7",
+		},
+		DISK_DETACH_POLICY_DETACH: {
+			"GET /disks/7",
+			"POST /disks/7/detach_from_instance_ticket",
+			"DELETE /disks/7",
+		},
+		DISK_DETACH_POLICY_DETACH_AFTER_STOP: {
+			"GET /disks/7",
+			"GET /instances/5",
+			"POST /instances/5/shutdown_ticket",
+			"POST /disks/7/detach_from_instance_ticket",
+			"POST /instances/5/power_on_ticket",
+			"DELETE /disks/7",
+		},
+	}
+	for policy, expected := range cases {
+		requests = nil
+		d := schema.TestResourceDataRaw(t, resourceDisk().Schema, map[string]interface{}{
+			"detach_policy": policy,
+		})
+		d.SetId("7")
+		diags := resourceDiskDelete(context.Background(), d, config)
+		if diags.HasError() != (policy == DISK_DETACH_POLICY_FAIL) {
+			t.Errorf("%s: unexpected result %v", policy, diags)
+		}
+		if fmt.Sprint(requests) != fmt.Sprint(expected) {
+			t.Errorf("%s: expected requests %v, got %v", policy, expected, requests)
+		}
+	}
+}
+
+func TestResourceDiskDeleteAlreadyRemoved(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusForbidden} {
+		config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && r.URL.Path == "/disks/7" {
+				w.WriteHeader(status)
+				return
+			}
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+
+		d := schema.TestResourceDataRaw(t, resourceDisk().Schema, map[string]interface{}{})
+		d.SetId("7")
+		if diags := resourceDiskDelete(context.Background(), d, config); diags.HasError() {
+			t.Errorf("%d: unexpected error %v", status, diags)
+		}
+		if d.Id() != "" {
+			t.Errorf("%d: expected disk to be removed from state", status)
+		}
+	}
+}
+
 func testAccCheckDiskExists(resourceName string, disk *odk.Disk) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[resourceName]
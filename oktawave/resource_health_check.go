@@ -0,0 +1,557 @@
+package oktawave
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/oktawave-code/odk"
+)
+
+const (
+	HEALTH_CHECK_TYPE_HTTP  = "http"
+	HEALTH_CHECK_TYPE_HTTPS = "https"
+	HEALTH_CHECK_TYPE_TCP   = "tcp"
+	HEALTH_CHECK_TYPE_PING  = "ping"
+)
+
+// healthCheckData holds fields shared by protocol specific health check models.
+type healthCheckData struct {
+	name                     string
+	address                  string
+	description              string
+	interval                 int32
+	errorTolerance           int32
+	port                     int32
+	timeout                  int32
+	httpMethodId             int32
+	contentRegularExpression string
+	notificationTimeId       int32
+	paused                   bool
+	locationsFailoverEnabled bool
+	serviceType              *odk.DictionaryItem
+	state                    *odk.DictionaryItem
+	suspended                bool
+	detailsLocation          string
+	lastValidCheck           time.Time
+	lastInvalidCheck         time.Time
+}
+
+func resourceHealthCheck() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceHealthCheckCreate,
+		ReadContext:   resourceHealthCheckRead,
+		UpdateContext: resourceHealthCheckUpdate,
+		DeleteContext: resourceHealthCheckDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceHealthCheckImport,
+		},
+		Schema: map[string]*schema.Schema{
+			// Required
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Health check name.",
+			},
+			"type": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{HEALTH_CHECK_TYPE_HTTP, HEALTH_CHECK_TYPE_HTTPS, HEALTH_CHECK_TYPE_TCP, HEALTH_CHECK_TYPE_PING}, false),
+				Description:  "Checked protocol. One of http, https, tcp or ping.",
+			},
+			"address": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "URL or IP address of monitored service, e.g. instance ip_address. For http and https checks URL path tells which page is requested.",
+			},
+			// Optional
+			"port": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Computed:    true,
+				Description: "Checked port. Defaults to 80 for http and tcp, 443 for https. Not used by ping checks.",
+			},
+			"interval": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      60,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Time between checks in seconds.",
+			},
+			"timeout": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Computed:    true,
+				Description: "Time in milliseconds service has to respond in. Defaults to 7000 for http and https, 10000 for tcp and 3000 for ping.",
+			},
+			"error_tolerance": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      51,
+				ValidateFunc: validation.IntBetween(1, 100),
+				Description:  "Percent of monitoring locations which have to report an error to consider service broken.",
+			},
+			"http_method_id": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     DICT_HTTP_METHOD_GET,
+				Description: "Request method of http and https checks. Value from dictionary #166",
+			},
+			"content_regular_expression": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Response of http and https checks must match this expression.",
+			},
+			"notification_time_id": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     DICT_NOTIFICATION_TIME_DEFAULT,
+				Description: "Tells when notification is sent.",
+			},
+			"paused": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Pauses health check.",
+			},
+			"locations_failover_enabled": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Use random substitute locations when monitoring location breaks down.",
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Health check description.",
+			},
+			// Computed
+			"service_type_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Service type id.",
+			},
+			"state_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Current state of checked service.",
+			},
+			"suspended": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Tells if health check is suspended.",
+			},
+			"details_location": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Url of health check details.",
+			},
+			"last_valid_check": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Date of last successful check.",
+			},
+			"last_invalid_check": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Date of last failed check.",
+			},
+		},
+		Description: "Health check monitoring service availability from Oktawave monitoring locations. Oktawave API doesn't allow to connect it to instance or load balancer, so it's bound to them by address only. Import id has <type>/<id> format.",
+	}
+}
+
+func resourceHealthCheckCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "creating health check")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	data := makeHealthCheckData(d)
+	var id int32
+	var err error
+	switch checkType := d.Get("type").(string); checkType {
+	case HEALTH_CHECK_TYPE_HTTP:
+		tflog.Debug(ctx, "calling ODK WatchApi.WatchCreateHttpHealthCheck")
+		var healthCheck odk.HealthCheckHttp
+		healthCheck, _, err = client.WatchApi.WatchCreateHttpHealthCheck(*auth, makeHttpHealthCheckCommand(data))
+		id = healthCheck.Id
+	case HEALTH_CHECK_TYPE_HTTPS:
+		tflog.Debug(ctx, "calling ODK WatchApi.WatchCreateHttpsHealthCheck")
+		var healthCheck odk.HealthCheckHttp
+		healthCheck, _, err = client.WatchApi.WatchCreateHttpsHealthCheck(*auth, odk.CreateUpdateHealthCheckHttpsCommand(makeHttpHealthCheckCommand(data)))
+		id = healthCheck.Id
+	case HEALTH_CHECK_TYPE_TCP:
+		tflog.Debug(ctx, "calling ODK WatchApi.WatchCreateTcpHealthCheck")
+		var healthCheck odk.HealthCheckTcp
+		healthCheck, _, err = client.WatchApi.WatchCreateTcpHealthCheck(*auth, makeTcpHealthCheckCommand(data))
+		id = healthCheck.Id
+	case HEALTH_CHECK_TYPE_PING:
+		tflog.Debug(ctx, "calling ODK WatchApi.WatchCreatePingHealthCheck")
+		var healthCheck odk.HealthCheckPing
+		healthCheck, _, err = client.WatchApi.WatchCreatePingHealthCheck(*auth, makePingHealthCheckCommand(data))
+		id = healthCheck.Id
+	default:
+		return diag.Errorf("Unsupported health check type: %v", checkType)
+	}
+	if err != nil {
+		return diag.Errorf("ODK Error in WatchApi while creating %v health check. %s", d.Get("type"), err)
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("successfully created health check. id=%v", id))
+	d.SetId(strconv.Itoa(int(id)))
+
+	return resourceHealthCheckRead(ctx, d, m)
+}
+
+func resourceHealthCheckRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "reading health check")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	healthCheckId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.Errorf("Invalid health check id: %v %s", d.Id(), err)
+	}
+
+	data, resp, err := getHealthCheckData(ctx, client, auth, d.Get("type").(string), int32(healthCheckId))
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			tflog.Warn(ctx, fmt.Sprintf("Health check %v not found, removing it from state", healthCheckId))
+			d.SetId("")
+			return nil
+		}
+		return diag.FromErr(err)
+	}
+
+	return loadHealthCheckData(ctx, d, m, data)
+}
+
+func resourceHealthCheckUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "updating health check")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	healthCheckId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.Errorf("Invalid health check id: %v %s", d.Id(), err)
+	}
+
+	data := makeHealthCheckData(d)
+	switch checkType := d.Get("type").(string); checkType {
+	case HEALTH_CHECK_TYPE_HTTP:
+		tflog.Debug(ctx, "calling ODK WatchApi.WatchUpdateHttpHealthCheck", map[string]interface{}{"id": healthCheckId})
+		_, _, err = client.WatchApi.WatchUpdateHttpHealthCheck(*auth, int32(healthCheckId), makeHttpHealthCheckCommand(data))
+	case HEALTH_CHECK_TYPE_HTTPS:
+		tflog.Debug(ctx, "calling ODK WatchApi.WatchUpdateHttpsHealthCheck", map[string]interface{}{"id": healthCheckId})
+		_, _, err = client.WatchApi.WatchUpdateHttpsHealthCheck(*auth, int32(healthCheckId), odk.CreateUpdateHealthCheckHttpsCommand(makeHttpHealthCheckCommand(data)))
+	case HEALTH_CHECK_TYPE_TCP:
+		tflog.Debug(ctx, "calling ODK WatchApi.WatchUpdateTcpHealthCheck", map[string]interface{}{"id": healthCheckId})
+		_, _, err = client.WatchApi.WatchUpdateTcpHealthCheck(*auth, int32(healthCheckId), makeTcpHealthCheckCommand(data))
+	case HEALTH_CHECK_TYPE_PING:
+		tflog.Debug(ctx, "calling ODK WatchApi.WatchUpdatePingHealthCheck", map[string]interface{}{"id": healthCheckId})
+		_, _, err = client.WatchApi.WatchUpdatePingHealthCheck(*auth, int32(healthCheckId), makePingHealthCheckCommand(data))
+	default:
+		return diag.Errorf("Unsupported health check type: %v", checkType)
+	}
+	if err != nil {
+		return diag.Errorf("ODK Error in WatchApi while updating %v health check. %s", d.Get("type"), err)
+	}
+
+	return resourceHealthCheckRead(ctx, d, m)
+}
+
+func resourceHealthCheckDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "deleting health check")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	healthCheckId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.Errorf("Invalid health check id: %v %s", d.Id(), err)
+	}
+
+	tflog.Debug(ctx, "calling ODK WatchApi.WatchDeleteHealthCheck", map[string]interface{}{"id": healthCheckId})
+	_, err = client.WatchApi.WatchDeleteHealthCheck(*auth, int32(healthCheckId))
+	if err != nil && err.Error() != "EOF" { // "EOF" condition is a patch for ODK 1.4 bug: it reports error when API returns empty body
+		return diag.Errorf("ODK Error in WatchApi.WatchDeleteHealthCheck. %s", err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// resourceHealthCheckImport accepts <type>/<id>, as type specific details can't be fetched without type.
+func resourceHealthCheckImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.SplitN(d.Id(), "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid health check import id %q, expected <type>/<id>", d.Id())
+	}
+	if d.Set("type", parts[0]) != nil {
+		return nil, fmt.Errorf("can't set health check type")
+	}
+	d.SetId(parts[1])
+	return []*schema.ResourceData{d}, nil
+}
+
+func makeHealthCheckData(d *schema.ResourceData) healthCheckData {
+	data := healthCheckData{
+		name:                     d.Get("name").(string),
+		address:                  d.Get("address").(string),
+		description:              d.Get("description").(string),
+		interval:                 int32(d.Get("interval").(int)),
+		errorTolerance:           int32(d.Get("error_tolerance").(int)),
+		port:                     int32(d.Get("port").(int)),
+		timeout:                  int32(d.Get("timeout").(int)),
+		httpMethodId:             int32(d.Get("http_method_id").(int)),
+		contentRegularExpression: d.Get("content_regular_expression").(string),
+		notificationTimeId:       int32(d.Get("notification_time_id").(int)),
+		paused:                   d.Get("paused").(bool),
+		locationsFailoverEnabled: d.Get("locations_failover_enabled").(bool),
+	}
+	// apply API defaults, zero values are rejected
+	checkType := d.Get("type").(string)
+	if data.port == 0 {
+		data.port = 80
+		if checkType == HEALTH_CHECK_TYPE_HTTPS {
+			data.port = 443
+		}
+	}
+	if data.timeout == 0 {
+		switch checkType {
+		case HEALTH_CHECK_TYPE_TCP:
+			data.timeout = 10000
+		case HEALTH_CHECK_TYPE_PING:
+			data.timeout = 3000
+		default:
+			data.timeout = 7000
+		}
+	}
+	return data
+}
+
+func makeHttpHealthCheckCommand(data healthCheckData) odk.CreateUpdateHealthCheckHttpCommand {
+	return odk.CreateUpdateHealthCheckHttpCommand{
+		HttpMethodId:             data.httpMethodId,
+		ContentRegularExpression: data.contentRegularExpression,
+		Port:                     data.port,
+		Timeout:                  data.timeout,
+		ErrorTolerance:           data.errorTolerance,
+		Name:                     data.name,
+		Address:                  data.address,
+		Interval:                 data.interval,
+		Paused:                   data.paused,
+		LocationsFailoverEnabled: data.locationsFailoverEnabled,
+		NotificationTimeId:       data.notificationTimeId,
+		Description:              data.description,
+	}
+}
+
+func makeTcpHealthCheckCommand(data healthCheckData) odk.CreateUpdateHealthCheckTcpCommand {
+	return odk.CreateUpdateHealthCheckTcpCommand{
+		Port:                     data.port,
+		Timeout:                  data.timeout,
+		ErrorTolerance:           data.errorTolerance,
+		Name:                     data.name,
+		Address:                  data.address,
+		Interval:                 data.interval,
+		Paused:                   data.paused,
+		LocationsFailoverEnabled: data.locationsFailoverEnabled,
+		NotificationTimeId:       data.notificationTimeId,
+		Description:              data.description,
+	}
+}
+
+func makePingHealthCheckCommand(data healthCheckData) odk.CreateUpdateHealthCheckPingCommand {
+	return odk.CreateUpdateHealthCheckPingCommand{
+		PackageSize:              64,
+		ReplyTimeout:             data.timeout,
+		ErrorTolerance:           data.errorTolerance,
+		Name:                     data.name,
+		Address:                  data.address,
+		Interval:                 data.interval,
+		Paused:                   data.paused,
+		LocationsFailoverEnabled: data.locationsFailoverEnabled,
+		NotificationTimeId:       data.notificationTimeId,
+		Description:              data.description,
+	}
+}
+
+func getHealthCheckData(ctx context.Context, client odk.APIClient, auth *context.Context, checkType string, healthCheckId int32) (healthCheckData, *http.Response, error) {
+	switch checkType {
+	case HEALTH_CHECK_TYPE_HTTP, HEALTH_CHECK_TYPE_HTTPS:
+		var healthCheck odk.HealthCheckHttp
+		var resp *http.Response
+		var err error
+		if checkType == HEALTH_CHECK_TYPE_HTTP {
+			tflog.Debug(ctx, "calling ODK WatchApi.WatchGetHttpHealthCheck", map[string]interface{}{"id": healthCheckId})
+			healthCheck, resp, err = client.WatchApi.WatchGetHttpHealthCheck(*auth, healthCheckId, nil)
+		} else {
+			tflog.Debug(ctx, "calling ODK WatchApi.WatchGetHttpsHealthCheck", map[string]interface{}{"id": healthCheckId})
+			healthCheck, resp, err = client.WatchApi.WatchGetHttpsHealthCheck(*auth, healthCheckId, nil)
+		}
+		if err != nil {
+			return healthCheckData{}, resp, fmt.Errorf("ODK Error in WatchApi while reading %v health check. %s", checkType, err)
+		}
+		data := healthCheckData{
+			name:                     healthCheck.Name,
+			address:                  healthCheck.Address,
+			description:              healthCheck.Description,
+			interval:                 healthCheck.Interval,
+			errorTolerance:           healthCheck.ErrorTolerance,
+			port:                     healthCheck.Port,
+			timeout:                  healthCheck.Timeout,
+			contentRegularExpression: healthCheck.ContentRegularExpression,
+			paused:                   healthCheck.Paused,
+			locationsFailoverEnabled: healthCheck.LocationsFailoverEnabled,
+			serviceType:              healthCheck.ServiceType,
+			state:                    healthCheck.State,
+			suspended:                healthCheck.Suspended,
+			detailsLocation:          healthCheck.DetailsLocation,
+			lastValidCheck:           healthCheck.LastValidCheck,
+			lastInvalidCheck:         healthCheck.LastInvalidCheck,
+		}
+		if healthCheck.HttpMethod != nil {
+			data.httpMethodId = healthCheck.HttpMethod.Id
+		}
+		if healthCheck.NotificationTime != nil {
+			data.notificationTimeId = healthCheck.NotificationTime.Id
+		}
+		return data, resp, nil
+	case HEALTH_CHECK_TYPE_TCP:
+		tflog.Debug(ctx, "calling ODK WatchApi.WatchGetTcpHealthCheck", map[string]interface{}{"id": healthCheckId})
+		healthCheck, resp, err := client.WatchApi.WatchGetTcpHealthCheck(*auth, healthCheckId, nil)
+		if err != nil {
+			return healthCheckData{}, resp, fmt.Errorf("ODK Error in WatchApi.WatchGetTcpHealthCheck. %s", err)
+		}
+		data := healthCheckData{
+			name:                     healthCheck.Name,
+			address:                  healthCheck.Address,
+			description:              healthCheck.Description,
+			interval:                 healthCheck.Interval,
+			errorTolerance:           healthCheck.ErrorTolerance,
+			port:                     healthCheck.Port,
+			timeout:                  healthCheck.Timeout,
+			paused:                   healthCheck.Paused,
+			locationsFailoverEnabled: healthCheck.LocationsFailoverEnabled,
+			serviceType:              healthCheck.ServiceType,
+			state:                    healthCheck.State,
+			suspended:                healthCheck.Suspended,
+			detailsLocation:          healthCheck.DetailsLocation,
+			lastValidCheck:           healthCheck.LastValidCheck,
+			lastInvalidCheck:         healthCheck.LastInvalidCheck,
+		}
+		if healthCheck.NotificationTime != nil {
+			data.notificationTimeId = healthCheck.NotificationTime.Id
+		}
+		return data, resp, nil
+	case HEALTH_CHECK_TYPE_PING:
+		tflog.Debug(ctx, "calling ODK WatchApi.WatchGetPingHealthCheck", map[string]interface{}{"id": healthCheckId})
+		healthCheck, resp, err := client.WatchApi.WatchGetPingHealthCheck(*auth, healthCheckId, nil)
+		if err != nil {
+			return healthCheckData{}, resp, fmt.Errorf("ODK Error in WatchApi.WatchGetPingHealthCheck. %s", err)
+		}
+		data := healthCheckData{
+			name:                     healthCheck.Name,
+			address:                  healthCheck.Address,
+			description:              healthCheck.Description,
+			interval:                 healthCheck.Interval,
+			errorTolerance:           healthCheck.ErrorTolerance,
+			timeout:                  healthCheck.ReplyTimeout,
+			paused:                   healthCheck.Paused,
+			locationsFailoverEnabled: healthCheck.LocationsFailoverEnabled,
+			serviceType:              healthCheck.ServiceType,
+			state:                    healthCheck.State,
+			suspended:                healthCheck.Suspended,
+			detailsLocation:          healthCheck.DetailsLocation,
+			lastValidCheck:           healthCheck.LastValidCheck,
+			lastInvalidCheck:         healthCheck.LastInvalidCheck,
+		}
+		if healthCheck.NotificationTime != nil {
+			data.notificationTimeId = healthCheck.NotificationTime.Id
+		}
+		return data, resp, nil
+	}
+	return healthCheckData{}, nil, fmt.Errorf("unsupported health check type: %v", checkType)
+}
+
+func loadHealthCheckData(ctx context.Context, d *schema.ResourceData, m interface{}, data healthCheckData) diag.Diagnostics {
+	// Store everything
+	tflog.Debug(ctx, "Parsing returned data")
+	if d.Set("name", data.name) != nil {
+		return diag.Errorf("Can't retrieve health check name")
+	}
+	if d.Set("address", data.address) != nil {
+		return diag.Errorf("Can't retrieve address")
+	}
+	if d.Set("description", data.description) != nil {
+		return diag.Errorf("Can't retrieve description")
+	}
+	if d.Set("interval", data.interval) != nil {
+		return diag.Errorf("Can't retrieve interval")
+	}
+	if d.Set("error_tolerance", data.errorTolerance) != nil {
+		return diag.Errorf("Can't retrieve error tolerance")
+	}
+	if d.Get("type").(string) != HEALTH_CHECK_TYPE_PING {
+		if d.Set("port", data.port) != nil {
+			return diag.Errorf("Can't retrieve port")
+		}
+	}
+	if d.Set("timeout", data.timeout) != nil {
+		return diag.Errorf("Can't retrieve timeout")
+	}
+	if data.httpMethodId != 0 {
+		if d.Set("http_method_id", data.httpMethodId) != nil {
+			return diag.Errorf("Can't retrieve http method")
+		}
+	}
+	if d.Set("content_regular_expression", data.contentRegularExpression) != nil {
+		return diag.Errorf("Can't retrieve content regular expression")
+	}
+	if data.notificationTimeId != 0 {
+		if d.Set("notification_time_id", data.notificationTimeId) != nil {
+			return diag.Errorf("Can't retrieve notification time")
+		}
+	}
+	if d.Set("paused", data.paused) != nil {
+		return diag.Errorf("Can't retrieve paused state")
+	}
+	if d.Set("locations_failover_enabled", data.locationsFailoverEnabled) != nil {
+		return diag.Errorf("Can't retrieve locations failover state")
+	}
+	if data.serviceType != nil {
+		if d.Set("service_type_id", data.serviceType.Id) != nil {
+			return diag.Errorf("Can't retrieve service type")
+		}
+	}
+	if data.state != nil {
+		if d.Set("state_id", data.state.Id) != nil {
+			return diag.Errorf("Can't retrieve state")
+		}
+	}
+	if d.Set("suspended", data.suspended) != nil {
+		return diag.Errorf("Can't retrieve suspended state")
+	}
+	if d.Set("details_location", data.detailsLocation) != nil {
+		return diag.Errorf("Can't retrieve details location")
+	}
+	if d.Set("last_valid_check", data.lastValidCheck.String()) != nil {
+		return diag.Errorf("Can't retrieve last valid check date")
+	}
+	if d.Set("last_invalid_check", data.lastInvalidCheck.String()) != nil {
+		return diag.Errorf("Can't retrieve last invalid check date")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package oktawave
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func testAccHealthCheckConfig(healthCheckName string, interval int) string {
+	return fmt.Sprintf(`
+resource "oktawave_ip" "test-ip" {
+	subregion_id = 1
+}
+
+resource "oktawave_instance" "test-instance" {
+	name = "test-health-check-instance"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	public_ips = [oktawave_ip.test-ip.id]
+}
+
+resource "oktawave_health_check" "test-health-check" {
+	name = "%s"
+	type = "http"
+	address = "http://${oktawave_instance.test-instance.ip_address}/status"
+	interval = %d
+	timeout = 5000
+	error_tolerance = 60
+	paused = true
+}
+`, healthCheckName, interval)
+}
+
+func TestAccOktawaveHealthCheck_Basic(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckHealthCheckDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccHealthCheckConfig("test-health-check", 60),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_health_check.test-health-check", "name", "test-health-check"),
+					resource.TestCheckResourceAttr("oktawave_health_check.test-health-check", "port", "80"),
+					resource.TestCheckResourceAttr("oktawave_health_check.test-health-check", "interval", "60"),
+					resource.TestCheckResourceAttr("oktawave_health_check.test-health-check", "timeout", "5000"),
+					resource.TestCheckResourceAttr("oktawave_health_check.test-health-check", "error_tolerance", "60"),
+					resource.TestCheckResourceAttrSet("oktawave_health_check.test-health-check", "service_type_id"),
+				),
+			},
+			{
+				Config: testAccHealthCheckConfig("test-health-check-renamed", 120),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_health_check.test-health-check", "name", "test-health-check-renamed"),
+					resource.TestCheckResourceAttr("oktawave_health_check.test-health-check", "interval", "120"),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckHealthCheckDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(*ClientConfig).odkClient
+	auth := testAccProvider.Meta().(*ClientConfig).odkAuth
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "oktawave_health_check" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(rs.Primary.ID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Failed to parse resource id %s.", rs.Primary.ID)
+		}
+
+		_, resp, err := client.WatchApi.WatchGetHealthCheck(*auth, int32(id), nil)
+		if err == nil {
+			return fmt.Errorf("Health check with id %d not destroyed correctly.", id)
+		}
+		if resp == nil || (resp.StatusCode != 404 && resp.StatusCode != 403) {
+			return fmt.Errorf("Failed to call for health check. Caused by: %s.", err)
+		}
+	}
+	return testAccCheckInstanceDatasourceDestroy(s)
+}
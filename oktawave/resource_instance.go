@@ -1,16 +1,25 @@
 package oktawave
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"math"
+	"net"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/oktawave-code/odk"
 )
 
@@ -20,8 +29,9 @@ func resourceInstance() *schema.Resource {
 		ReadContext:   resourceInstanceRead,
 		UpdateContext: resourceInstanceUpdate,
 		DeleteContext: resourceInstanceDelete,
+		CustomizeDiff: resourceInstanceCustomizeDiff,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importStatePassthroughWithDeletionProtection,
 		},
 		Schema: map[string]*schema.Schema{
 			// Required
@@ -43,7 +53,7 @@ func resourceInstance() *schema.Resource {
 			"template_id": {
 				Type:        schema.TypeInt,
 				Required:    true,
-				Description: "Defines which image will be used for instance initialization. User can use standard image with one of popular operating systems or choose its own template. ID from templates resource",
+				Description: "Defines which image will be used for instance initialization. User can use standard image with one of popular operating systems or choose its own template. ID from templates resource. Can't be changed after instance is created.",
 			},
 			"type_id": {
 				Type:        schema.TypeInt,
@@ -77,9 +87,35 @@ func resourceInstance() *schema.Resource {
 				Description: "Allows to create instance without public IP. In this case this instance must be in at least one OPN.",
 			},
 			"init_script": {
-				Type:        schema.TypeString,
+				Type:          schema.TypeString,
+				Optional:      true,
+				Sensitive:     true,
+				ConflictsWith: []string{"user_data", "user_data_base64"},
+				Description:   "Must be base64 encoded. This script will be invoked during instance initialization. Consider using user_data or user_data_base64 instead.",
+			},
+			"user_data": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"init_script", "user_data_base64"},
+				StateFunc:     userDataStateFunc,
+				Description:   "Plain text script (e.g. shell script or cloud-config) invoked during instance initialization. Provider encodes it before sending. Only its hash is stored in state.",
+			},
+			"user_data_base64": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"init_script", "user_data"},
+				StateFunc:     userDataStateFunc,
+				ValidateFunc:  validation.StringIsBase64,
+				Description:   "Base64 encoded script invoked during instance initialization. Use it for binary content, like output of oktawave_cloudinit_config data source. Only its hash is stored in state.",
+			},
+			"user_data_gzip": {
+				Type:        schema.TypeBool,
 				Optional:    true,
-				Description: "Must be base64 encoded. This script will be invoked during instance initialization.",
+				ForceNew:    true,
+				Default:     false,
+				Description: "Compress user_data or user_data_base64 content with gzip before sending. Helps with large scripts.",
 			},
 			"ssh_keys_ids": {
 				Type:     schema.TypeSet,
@@ -89,6 +125,24 @@ func resourceInstance() *schema.Resource {
 				},
 				Description: "List of ssh keys injected to this instance during initialization.",
 			},
+			"wait_for_status": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Description:  "After creation wait until instance reaches this status. Value from dictionary #27",
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"wait_for_tools": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Description:  "After creation wait until VMware tools reach this status. Value from dictionary #155",
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"wait_for_tcp_port": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Description:  "After creation wait until this TCP port accepts connections on instance ip_address (or private_ip_address for instances without public IP).",
+				ValidateFunc: validation.IsPortNumber,
+			},
 			"public_ips": {
 				Type:     schema.TypeSet,
 				Optional: true,
@@ -96,8 +150,46 @@ func resourceInstance() *schema.Resource {
 				Elem: &schema.Schema{
 					Type: schema.TypeInt,
 				},
-				Description: "List of public IPs attached to this instance.",
+				Description: "List of public IPs attached to this instance. Leave it unset for IPs managed by oktawave_ip_attachment.",
+			},
+			"data_disk": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Disk name.",
+						},
+						"tier_id": {
+							Type:        schema.TypeInt,
+							Required:    true,
+							Description: "Defines disk performance class. Value from dictionary #17",
+						},
+						"capacity": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      5,
+							ValidateFunc: validation.IntAtLeast(5),
+							Description:  "Disk size in GB. At least 5 GB. Disk capacity can be only scaled up.",
+						},
+						"delete_on_termination": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     true,
+							Description: "Delete disk when its block is removed or instance is destroyed. When false, disk is only detached and left in account.",
+						},
+						"id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Id of disk.",
+						},
+					},
+				},
+				Description: "Extra disks created in instance subregion and attached to this instance. Blocks are matched with disks by position, so add and remove them at the end of list. Disks managed here are not listed in disks_ids.",
 			},
+			"deletion_protection": deletionProtectionSchema(),
 			// Computed
 			"system_disk_id": {
 				Type:        schema.TypeInt,
@@ -126,12 +218,12 @@ func resourceInstance() *schema.Resource {
 			"is_locked": {
 				Type:        schema.TypeBool,
 				Computed:    true,
-				Description: "Tells if instance is locked.",
+				Description: "Tells if instance is locked by running operation. Oktawave API doesn't allow to lock instance on demand.",
 			},
 			"locking_date": {
 				Type:        schema.TypeString,
 				Computed:    true,
-				Description: "Tells when instance was locked.",
+				Description: "Tells when running operation locked instance.",
 			},
 			"ip_address": {
 				Type:        schema.TypeString,
@@ -186,12 +278,13 @@ func resourceInstance() *schema.Resource {
 			"payment_type_id": {
 				Type:        schema.TypeInt,
 				Computed:    true,
-				Description: "Value from dictionary #11",
+				Description: "Value from dictionary #11. Oktawave API doesn't allow to choose it, it's managed in customer panel.",
 			},
 			"scsi_controller_type_id": {
 				Type:        schema.TypeInt,
+				Optional:    true,
 				Computed:    true,
-				Description: "Value from dictionary #182",
+				Description: "Value from dictionary #182. When set, it is applied right after instance is created.",
 			},
 			"total_disks_capacity": {
 				Type:        schema.TypeInt,
@@ -211,26 +304,28 @@ func resourceInstance() *schema.Resource {
 			"health_check_id": {
 				Type:        schema.TypeInt,
 				Computed:    true,
-				Description: "Id of connected healthcheck.",
+				Description: "Id of connected healthcheck. Oktawave API doesn't allow to connect it.",
 			},
 			"support_type_id": {
 				Type:        schema.TypeInt,
 				Computed:    true,
-				Description: "Support type id.",
+				Description: "Support type id. Oktawave API doesn't allow to choose it, it's managed in customer panel.",
 			},
 			"disks_ids": {
 				Type:     schema.TypeSet,
 				Optional: true,
+				Computed: true,
 				Elem: &schema.Schema{
 					Type: schema.TypeInt,
 				},
-				Description: "Ids of connected disks.",
+				Description: "Ids of connected disks. When not set, attached disks (e.g. by oktawave_disk_attachment) are only reported. Empty list detaches all disks.",
 			},
 			"converted_to_template_id": {
 				Type:        schema.TypeInt,
 				Computed:    true,
 				Optional:    true,
-				Description: "Id of the template this instance was converted to. When instance is converted to template it ceases to exist and this attribute is set. After this, instance state will not be synchronized to prevent instance recreation. Instance definition may be safely removed from definition and state.",
+				Deprecated:  "Instances converted to template are removed from state on next refresh, this attribute is no longer set.",
+				Description: "Id of the template this instance was converted to. Kept only for states written by older provider versions.",
 			},
 		},
 		Timeouts: &schema.ResourceTimeout{
@@ -242,6 +337,7 @@ func resourceInstance() *schema.Resource {
 
 func resourceInstanceCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	tflog.Info(ctx, "creating instance")
+	deadline := time.Now().Add(d.Timeout(schema.TimeoutCreate))
 
 	err := validateInstanceResource(d)
 	if err != nil {
@@ -272,9 +368,14 @@ func resourceInstanceCreate(ctx context.Context, d *schema.ResourceData, m inter
 		InstancesCount:        1,
 		WithoutPublicIp:       (bool)(d.Get("without_public_ip").(bool)),
 		IPAddressId:           initialIpAddress,
-		InitScript:            d.Get("init_script").(string),
 	}
 
+	initScript, err := getInstanceInitScript(d)
+	if err != nil {
+		return diag.Errorf("Can't prepare user data. %s", err)
+	}
+	createCommand.InitScript = initScript
+
 	if authorizationMethod == DICT_LOGIN_TYPE_SSH_KEYS {
 		sshKeyIds := castToInt32(d.Get("ssh_keys_ids").(*schema.Set).List())
 		if len(sshKeyIds) == 0 {
@@ -311,6 +412,19 @@ func resourceInstanceCreate(ctx context.Context, d *schema.ResourceData, m inter
 	tflog.Info(ctx, fmt.Sprintf("successfully created OCI. id=%v", createTicket.ObjectId))
 	d.SetId(strconv.Itoa(int(createTicket.ObjectId)))
 
+	if scsiControllerTypeId, ok := d.GetOk("scsi_controller_type_id"); ok {
+		tflog.Debug(ctx, "calling ODK OCIApi.InstancesGet_2", map[string]interface{}{"id": createTicket.ObjectId})
+		instance, _, err := client.OCIApi.InstancesGet_2(*auth, createTicket.ObjectId, nil)
+		if err != nil {
+			return diag.Errorf("ODK Error in OCIApi.InstancesGet_2. %s", err)
+		}
+		if instance.ScsiControllerType == nil || instance.ScsiControllerType.Id != int32(scsiControllerTypeId.(int)) {
+			if err := changeInstanceScsiControllerType(ctx, client, auth, createTicket.ObjectId, int32(scsiControllerTypeId.(int))); err != nil {
+				return diag.Errorf("Changing SCSI controller type failed. %s", err)
+			}
+		}
+	}
+
 	if len(ipAddressToAttach) > 0 {
 		err := attachInstanceToIps(client, auth, ipAddressToAttach, createTicket.ObjectId)
 		if err != nil {
@@ -328,6 +442,23 @@ func resourceInstanceCreate(ctx context.Context, d *schema.ResourceData, m inter
 		}
 	}
 
+	if dataDisks := d.Get("data_disk").([]interface{}); len(dataDisks) > 0 {
+		subregionId := int32(d.Get("subregion_id").(int))
+		current, err := applyInstanceDataDisks(ctx, client, auth, createTicket.ObjectId, subregionId, nil, dataDisks)
+		if d.Set("data_disk", current) != nil {
+			return diag.Errorf("Can't store data disks")
+		}
+		if err != nil {
+			return diag.Errorf("Creating data disks failed. %s", err)
+		}
+	}
+
+	if err := waitForInstanceReadiness(ctx, client, auth, d, createTicket.ObjectId, deadline); err != nil {
+		return diag.Errorf("Instance %v was created, but it is not ready. %s", createTicket.ObjectId, err)
+	}
+
+	// instance got its IPs, cached listing is outdated
+	m.(*ClientConfig).ipCache.invalidate()
 	return resourceInstanceRead(ctx, d, m)
 }
 
@@ -342,29 +473,23 @@ func resourceInstanceRead(ctx context.Context, d *schema.ResourceData, m interfa
 		return diag.Errorf("Invalid OCI id: %v %s", d.Id(), err)
 	}
 
-	// Check if instance was converted to template. When instance is converted to template
-	// it is no longer accessible on instances list. This is workaround to prevent instance recreation.
-	// In this situation if template is found for instance, any data will not be further synchronized.
-	// get template id
-	isConverted, templateId, err := isConvertedToTemplate(client, auth, d, int32(instanceId))
-	if err != nil {
-		return diag.FromErr(err)
+	// state written by provider versions which kept converted instances in state
+	if templateId, isConverted := d.GetOk("converted_to_template_id"); isConverted {
+		return dropConvertedInstance(ctx, d, int32(instanceId), int32(templateId.(int)))
 	}
 
-	if isConverted {
-		// get template id and set it, then print info and return
-		if d.Set("converted_to_template_id", templateId) != nil {
-			return diag.Errorf("Can't retrieve templateId")
-		}
-		tflog.Warn(ctx, "Instance was converted to template, definition may be safely removed from definition and state as it no longer exists.")
-		return nil
-	}
-	// End of template workaround
-
 	tflog.Debug(ctx, "calling ODK OCIApi.InstancesGet", map[string]interface{}{"id": instanceId})
 	instance, resp, err := client.OCIApi.InstancesGet_2(*auth, (int32)(instanceId), nil)
 	if err != nil {
 		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) { // api returns 403 on missing instance
+			// instance converted to template is consumed by conversion, template is the only trace of it
+			template, err := getInstanceTemplate(ctx, client, auth, int32(instanceId))
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			if template != nil {
+				return dropConvertedInstance(ctx, d, int32(instanceId), template.Id)
+			}
 			d.SetId("")
 		}
 		return diag.Errorf("Error while retrieving OCI %v (possibly \"not found\" error): %s", instanceId, err)
@@ -389,18 +514,6 @@ func resourceInstanceUpdate(ctx context.Context, d *schema.ResourceData, m inter
 		return diag.Errorf("Invalid OCI id: %v %s", d.Id(), err)
 	}
 
-	// Converted to template workaround. Instance no longer exists
-	isConverted, _, err := isConvertedToTemplate(client, auth, d, int32(instanceId))
-	if err != nil {
-		return diag.FromErr(err)
-	}
-
-	if isConverted {
-		tflog.Warn(ctx, "Instance was converted to template, definition may be safely removed from definition and state as it no longer exists. No changes will be applied.")
-		return nil
-	}
-	// End of workaround
-
 	if d.HasChange("name") {
 		tflog.Info(ctx, "instance name change detected")
 		newName := d.Get("name").(string)
@@ -449,6 +562,13 @@ func resourceInstanceUpdate(ctx context.Context, d *schema.ResourceData, m inter
 		}
 	}
 
+	if d.HasChange("scsi_controller_type_id") {
+		tflog.Info(ctx, "scsi controller type change detected")
+		if err := changeInstanceScsiControllerType(ctx, client, auth, int32(instanceId), int32(d.Get("scsi_controller_type_id").(int))); err != nil {
+			return diag.Errorf("Changing SCSI controller type failed. %s", err)
+		}
+	}
+
 	if d.HasChange("system_disk_size") || d.HasChange("system_disk_class_id") {
 		tflog.Info(ctx, "system disk size or class change detected")
 		systemDiskId := d.Get("system_disk_id").(int)
@@ -575,12 +695,32 @@ func resourceInstanceUpdate(ctx context.Context, d *schema.ResourceData, m inter
 		}
 	}
 
+	if d.HasChange("data_disk") {
+		tflog.Info(ctx, "data disks change detected")
+		oldDataDisks, newDataDisks := d.GetChange("data_disk")
+		subregionId := int32(d.Get("subregion_id").(int))
+		current, err := applyInstanceDataDisks(ctx, client, auth, int32(instanceId), subregionId, oldDataDisks.([]interface{}), newDataDisks.([]interface{}))
+		if d.Set("data_disk", current) != nil {
+			return diag.Errorf("Can't store data disks")
+		}
+		if err != nil {
+			return diag.Errorf("Updating data disks failed. %s", err)
+		}
+	}
+
+	if d.HasChange("public_ips") {
+		m.(*ClientConfig).ipCache.invalidate()
+	}
 	return resourceInstanceRead(ctx, d, m)
 }
 
 func resourceInstanceDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	tflog.Info(ctx, "deleting instance")
 
+	if diags := checkDeletionProtection(d); diags != nil {
+		return diags
+	}
+
 	client := m.(*ClientConfig).odkClient
 	auth := m.(*ClientConfig).odkAuth
 
@@ -589,19 +729,6 @@ func resourceInstanceDelete(ctx context.Context, d *schema.ResourceData, m inter
 		return diag.Errorf("Invalid OCI id: %v %s", d.Id(), err)
 	}
 
-	// Converted to template workaround. Instance no longer exists
-	isConverted, _, err := isConvertedToTemplate(client, auth, d, int32(instanceId))
-	if err != nil {
-		return diag.FromErr(err)
-	}
-
-	if isConverted {
-		// instance was converted to template and no longer exists, api should not be called
-		d.SetId("")
-		return nil
-	}
-	// End of workaround
-
 	// detach disks
 	if disksIdSet, disksIsSet := d.GetOk("disks_ids"); disksIsSet {
 		disksIds := castToInt32(disksIdSet.(*schema.Set).List())
@@ -613,21 +740,49 @@ func resourceInstanceDelete(ctx context.Context, d *schema.ResourceData, m inter
 		}
 	}
 
-	tflog.Debug(ctx, "calling ODK OCIApi.InstancesDelete")
-	deleteTicket, _, err := client.OCIApi.InstancesDelete(*auth, (int32)(instanceId), nil)
+	// detach data disks, delete those not meant to outlive instance
+	for _, rawDisk := range d.Get("data_disk").([]interface{}) {
+		if err := removeInstanceDataDisk(ctx, client, auth, int32(instanceId), rawDisk.(map[string]interface{})); err != nil {
+			return diag.Errorf("Removing data disks failed. %s", err)
+		}
+	}
+
+	err = deleteInstance(ctx, client, auth, int32(instanceId), false)
+	m.(*ClientConfig).ipCache.invalidate()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// deleteInstance deletes instance, with deep it also deletes additional disks still attached to it.
+func deleteInstance(ctx context.Context, client odk.APIClient, auth *context.Context, instanceId int32, deep bool) error {
+	var params map[string]interface{}
+	if deep {
+		params = map[string]interface{}{
+			"deep": true,
+		}
+	}
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesDelete", map[string]interface{}{"id": instanceId, "deep": deep})
+	deleteTicket, resp, err := client.OCIApi.InstancesDelete(*auth, instanceId, params)
 	if err != nil {
-		return diag.Errorf("ODK Error in OCIApi.InstancesDelete. %s", err)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			// already gone, e.g. converted to template within the same run
+			tflog.Warn(ctx, fmt.Sprintf("OCI %v not found, nothing to delete", instanceId))
+			return nil
+		}
+		return fmt.Errorf("ODK Error in OCIApi.InstancesDelete. %s", err)
 	}
 
 	deleteTicket, err = waitForTicket(client, auth, deleteTicket)
 	if err != nil {
-		return diag.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+		return fmt.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
 	}
 	if deleteTicket.Status.Id != DICT_TICKET_SUCCEED {
-		return diag.Errorf("Unable to delete instance. Ticket status=%v", deleteTicket.Status.Id)
+		return fmt.Errorf("unable to delete instance %v. Ticket status=%v", instanceId, deleteTicket.Status.Id)
 	}
-
-	d.SetId("")
 	return nil
 }
 
@@ -635,65 +790,113 @@ func loadInstanceData(ctx context.Context, d *schema.ResourceData, m interface{}
 	client := m.(*ClientConfig).odkClient
 	auth := m.(*ClientConfig).odkAuth
 
+	// Independent sub-reads run concurrently, each of them stores only its own results
+	var wg sync.WaitGroup
+	var disksList odk.ApiCollectionDisk
+	var disksResp *http.Response
+	var disksErr error
+	var opnMacMap map[string]string
+	var opnIds []int
+	var opnsErr error
+	var ips []odk.Ip
+	var ipsErr error
+	var keys odk.ApiCollectionInstanceSshKey
+	var keysErr error
+	var initScript string
+	var initScriptResp *http.Response
+	var initScriptErr error
+	wg.Add(5)
+	go func() {
+		defer wg.Done()
+		tflog.Debug(ctx, "calling ODK OCIApi.InstancesGetDisks", map[string]interface{}{"id": instance.Id})
+		params := map[string]interface{}{
+			"pageSize": int32(math.MaxInt16),
+		}
+		disksList, disksResp, disksErr = client.OCIApi.InstancesGetDisks(*auth, int32(instance.Id), params)
+	}()
+	go func() {
+		defer wg.Done()
+		opnMacMap, opnIds, opnsErr = getOpnsData(client, *auth, int32(instance.Id))
+	}()
+	go func() {
+		defer wg.Done()
+		ips, ipsErr = m.(*ClientConfig).ipCache.getInstanceIps(ctx, client, auth, int32(instance.Id))
+	}()
+	go func() {
+		defer wg.Done()
+		tflog.Debug(ctx, "calling ODK OCIApi.InstancesGetSshKeys")
+		params := map[string]interface{}{
+			"pageSize": int32(math.MaxInt16),
+		}
+		keys, _, keysErr = client.OCIApi.InstancesGetSshKeys(*auth, int32(instance.Id), params)
+	}()
+	go func() {
+		defer wg.Done()
+		tflog.Debug(ctx, "calling ODK OCIApi.InstancesGetInstanceInitScript")
+		initScript, initScriptResp, initScriptErr = client.OCIApi.InstancesGetInstanceInitScript(*auth, int32(instance.Id), nil)
+	}()
+	wg.Wait()
+
 	// Load system disk data
-	tflog.Debug(ctx, "calling ODK OCIApi.InstancesGetDisks", map[string]interface{}{"id": instance.Id})
-	params := map[string]interface{}{
-		"pageSize": int32(math.MaxInt16),
+	if disksErr != nil {
+		if disksResp != nil && disksResp.StatusCode == http.StatusNotFound {
+			return diag.Errorf("System disk for OCI %v not found", instance.Id)
+		}
+		return diag.Errorf("Error while retrieving system disk for OCI %v: %s", instance.Id, disksErr)
 	}
-	list, resp, err := client.OCIApi.InstancesGetDisks(*auth, int32(instance.Id), params)
 	var systemDisk odk.Disk
 	var customDisks []int32
-	for _, disk := range list.Items {
+	attachedDisks := make(map[int32]odk.Disk)
+	for _, disk := range disksList.Items {
 		for _, connection := range disk.Connections {
 			if connection.Instance.Id == instance.Id {
 				if connection.IsSystemDisk {
 					systemDisk = disk
 				} else {
-					customDisks = append(customDisks, disk.Id)
+					attachedDisks[disk.Id] = disk
 				}
 			}
 		}
 	}
-	if err != nil {
-		if resp != nil && resp.StatusCode == http.StatusNotFound {
-			return diag.Errorf("System disk for OCI %v not found", instance.Id)
+	// disks managed by data_disk blocks are not reported in disks_ids
+	dataDisks := make([]interface{}, 0)
+	for _, rawDisk := range d.Get("data_disk").([]interface{}) {
+		dataDisk := rawDisk.(map[string]interface{})
+		disk, ok := attachedDisks[int32(dataDisk["id"].(int))]
+		if !ok {
+			tflog.Warn(ctx, fmt.Sprintf("Data disk %v is no longer attached to instance %v", dataDisk["id"], instance.Id))
+			continue
 		}
-		return diag.Errorf("Error while retrieving system disk for OCI %v: %s", instance.Id, err)
+		delete(attachedDisks, disk.Id)
+		dataDisk["name"] = disk.Name
+		dataDisk["capacity"] = int(disk.SpaceCapacity)
+		dataDisk["tier_id"] = int(disk.Tier.Id)
+		dataDisks = append(dataDisks, dataDisk)
 	}
-
-	// Load networking data
-	opnMacMap, opnIds, err := getOpnsData(client, *auth, int32(instance.Id))
-	if err != nil {
-		return diag.Errorf("failed to load OPNs. %s", err)
+	for diskId := range attachedDisks {
+		customDisks = append(customDisks, diskId)
 	}
 
-	tflog.Debug(context.Background(), "calling ODK FloatingIPsApi.FloatingIpsGetIp")
-	params2 := map[string]interface{}{
-		"instanceId": instance.Id,
-		"pageSize":   int32(math.MaxInt16),
+	// Load networking data
+	if opnsErr != nil {
+		return diag.Errorf("failed to load OPNs. %s", opnsErr)
 	}
-	ips, _, err := client.FloatingIPsApi.FloatingIpsGetIps(*auth, params2)
-	if err != nil {
-		return diag.Errorf("ODK Error in FloatingIPsApi.FloatingIpsGetIp. %s", err)
+	if ipsErr != nil {
+		return diag.FromErr(ipsErr)
 	}
 	publicIps := make([]int32, 0)
 	var ipMac *string = nil
-	for i, ip := range ips.Items {
+	for i, ip := range ips {
 		publicIps = append(publicIps, ip.Id)
 		if i == 0 {
 			// first public ip is used as instance mac address
-			ipMac = &ip.MacAddress
+			ipMac = &ips[i].MacAddress
 		}
 	}
 
 	// Load ssh keys
-	tflog.Debug(context.Background(), "calling ODK OCIApi.InstancesGetSshKeys")
-	params3 := map[string]interface{}{
-		"pageSize": int32(math.MaxInt16),
-	}
-	keys, _, err := client.OCIApi.InstancesGetSshKeys(*auth, int32(instance.Id), params3)
-	if err != nil {
-		return diag.Errorf("ODK Error in OCIApi.InstancesGetSshKeys. %s", err)
+	if keysErr != nil {
+		return diag.Errorf("ODK Error in OCIApi.InstancesGetSshKeys. %s", keysErr)
 	}
 	keyIds := make([]int32, 0)
 	for _, key := range keys.Items {
@@ -701,11 +904,9 @@ func loadInstanceData(ctx context.Context, d *schema.ResourceData, m interface{}
 	}
 
 	// Load init script
-	tflog.Debug(context.Background(), "calling ODK OCIApi.InstancesGetInstanceInitScript")
-	initScript, resp, err := client.OCIApi.InstancesGetInstanceInitScript(*auth, int32(instance.Id), nil)
-	if err != nil {
-		if resp.StatusCode != http.StatusNotFound {
-			return diag.Errorf("Error while retrieving init script for OCI %v: %s", instance.Id, err)
+	if initScriptErr != nil {
+		if initScriptResp == nil || initScriptResp.StatusCode != http.StatusNotFound {
+			return diag.Errorf("Error while retrieving init script for OCI %v: %s", instance.Id, initScriptErr)
 		}
 	}
 
@@ -734,8 +935,8 @@ func loadInstanceData(ctx context.Context, d *schema.ResourceData, m interface{}
 		return diag.Errorf("Can't retrieve system disk size")
 	}
 	// Not obtainable: without_public_ip
-	if d.Set("init_script", initScript) != nil {
-		return diag.Errorf("Can't retrieve init script")
+	if err := setInstanceInitScript(d, initScript); err != nil {
+		return diag.Errorf("Can't retrieve init script. %s", err)
 	}
 	if d.Set("ssh_keys_ids", keyIds) != nil {
 		return diag.Errorf("Can't retrieve ssh key ids")
@@ -819,9 +1020,182 @@ func loadInstanceData(ctx context.Context, d *schema.ResourceData, m interface{}
 	if d.Set("disks_ids", customDisks) != nil {
 		return diag.Errorf("Can't retrieve attached disks list")
 	}
+	if d.Set("data_disk", dataDisks) != nil {
+		return diag.Errorf("Can't retrieve data disks")
+	}
 	return nil
 }
 
+var instanceReadinessPollInterval = 10 * time.Second
+
+func waitForInstanceReadiness(ctx context.Context, client odk.APIClient, auth *context.Context, d *schema.ResourceData, instanceId int32, deadline time.Time) error {
+	statusId := int32(d.Get("wait_for_status").(int))
+	toolsStatusId := int32(d.Get("wait_for_tools").(int))
+	port := d.Get("wait_for_tcp_port").(int)
+	if statusId == 0 && toolsStatusId == 0 && port == 0 {
+		return nil
+	}
+
+	instance, err := waitForInstanceStatus(ctx, client, auth, instanceId, statusId, toolsStatusId, deadline)
+	if err != nil {
+		return err
+	}
+
+	if port != 0 {
+		address := instance.IpAddress
+		if address == "" {
+			address = instance.PrivateIpAddress
+		}
+		if address == "" {
+			return fmt.Errorf("instance has no ip address to check port %d", port)
+		}
+		if err := waitForTcpPort(ctx, address, port, deadline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForInstanceStatus polls instance until it reaches given status and tools status. Zero means "any".
+func waitForInstanceStatus(ctx context.Context, client odk.APIClient, auth *context.Context, instanceId int32, statusId int32, toolsStatusId int32, deadline time.Time) (odk.Instance, error) {
+	tflog.Info(ctx, fmt.Sprintf("Waiting for instance %v (status=%v; tools status=%v)", instanceId, statusId, toolsStatusId))
+	ticker := time.NewTicker(instanceReadinessPollInterval)
+	defer ticker.Stop()
+	for {
+		tflog.Debug(ctx, "calling ODK OCIApi.InstancesGet_2", map[string]interface{}{"id": instanceId})
+		instance, _, err := client.OCIApi.InstancesGet_2(*auth, instanceId, nil)
+		switch {
+		case err != nil:
+			tflog.Warn(ctx, fmt.Sprintf("ODK Error in OCIApi.InstancesGet_2. %v", err))
+		case statusId != 0 && (instance.Status == nil || instance.Status.Id != statusId):
+			tflog.Debug(ctx, "Still waiting for instance status")
+		case toolsStatusId != 0 && (instance.VmWareToolsStatus == nil || instance.VmWareToolsStatus.Id != toolsStatusId):
+			tflog.Debug(ctx, "Still waiting for VMware tools status")
+		default:
+			return instance, nil
+		}
+		if time.Now().Add(instanceReadinessPollInterval).After(deadline) {
+			return odk.Instance{}, fmt.Errorf("timeout while waiting for instance %v to reach status %v and tools status %v", instanceId, statusId, toolsStatusId)
+		}
+		select {
+		case <-ctx.Done():
+			return odk.Instance{}, fmt.Errorf("waiting for instance %v was interrupted. %s", instanceId, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func waitForTcpPort(ctx context.Context, address string, port int, deadline time.Time) error {
+	target := net.JoinHostPort(address, strconv.Itoa(port))
+	tflog.Info(ctx, fmt.Sprintf("Waiting for %s to accept connections", target))
+	dialer := net.Dialer{Timeout: instanceReadinessPollInterval}
+	ticker := time.NewTicker(instanceReadinessPollInterval)
+	defer ticker.Stop()
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", target)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		tflog.Debug(ctx, fmt.Sprintf("Still waiting for %s. %v", target, err))
+		if time.Now().Add(instanceReadinessPollInterval).After(deadline) {
+			return fmt.Errorf("timeout while waiting for %s to accept connections. %s", target, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s was interrupted. %s", target, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func getInstanceInitScript(d *schema.ResourceData) (string, error) {
+	useGzip := d.Get("user_data_gzip").(bool)
+	if userData, ok := d.GetOk("user_data"); ok {
+		return encodeUserData([]byte(userData.(string)), useGzip)
+	}
+	if userData, ok := d.GetOk("user_data_base64"); ok {
+		if !useGzip {
+			return userData.(string), nil
+		}
+		raw, err := base64.StdEncoding.DecodeString(userData.(string))
+		if err != nil {
+			return "", fmt.Errorf("user_data_base64 is not valid base64. %s", err)
+		}
+		return encodeUserData(raw, true)
+	}
+	return d.Get("init_script").(string), nil
+}
+
+// setInstanceInitScript stores init script returned by api in the attribute used in definition.
+// user_data and user_data_base64 are kept as hashes, so they are compared with hashed configuration.
+func setInstanceInitScript(d *schema.ResourceData, initScript string) error {
+	useGzip, _ := d.Get("user_data_gzip").(bool) // not present in oktawave_instance_clone
+	if _, ok := d.GetOk("user_data"); ok {
+		userData, err := decodeUserData(initScript, useGzip)
+		if err != nil {
+			return err
+		}
+		return d.Set("user_data", hashUserData(string(userData)))
+	}
+	if _, ok := d.GetOk("user_data_base64"); ok {
+		if !useGzip {
+			return d.Set("user_data_base64", hashUserData(initScript))
+		}
+		userData, err := decodeUserData(initScript, true)
+		if err != nil {
+			return err
+		}
+		return d.Set("user_data_base64", hashUserData(base64.StdEncoding.EncodeToString(userData)))
+	}
+	return d.Set("init_script", initScript)
+}
+
+func encodeUserData(userData []byte, useGzip bool) (string, error) {
+	if useGzip {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(userData); err != nil {
+			return "", err
+		}
+		if err := writer.Close(); err != nil {
+			return "", err
+		}
+		userData = buf.Bytes()
+	}
+	return base64.StdEncoding.EncodeToString(userData), nil
+}
+
+func decodeUserData(initScript string, useGzip bool) ([]byte, error) {
+	userData, err := base64.StdEncoding.DecodeString(initScript)
+	if err != nil {
+		return nil, fmt.Errorf("init script is not valid base64. %s", err)
+	}
+	if !useGzip {
+		return userData, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(userData))
+	if err != nil {
+		return nil, fmt.Errorf("init script is not gzip compressed. %s", err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func hashUserData(userData string) string {
+	hash := sha1.Sum([]byte(userData))
+	return hex.EncodeToString(hash[:])
+}
+
+func userDataStateFunc(v interface{}) string {
+	switch userData := v.(type) {
+	case string:
+		return hashUserData(userData)
+	default:
+		return ""
+	}
+}
+
 func checkSshKeysList(sshKeyIds []int32, acceptedSshKeys []odk.SshKey) error {
 	// Build accepted keys set
 	check := make(map[int32]struct{})
@@ -856,6 +1230,110 @@ func attachDiskToInstance(client odk.APIClient, auth *context.Context, diskId in
 	return nil
 }
 
+// applyInstanceDataDisks converges data disks attached to instance from oldDisks to newDisks, matching them by position.
+// It returns data disks that exist after the operation, also when it fails partway.
+func applyInstanceDataDisks(ctx context.Context, client odk.APIClient, auth *context.Context, instanceId int32, subregionId int32, oldDisks []interface{}, newDisks []interface{}) ([]interface{}, error) {
+	current := make([]interface{}, len(oldDisks))
+	copy(current, oldDisks)
+
+	for i := len(current) - 1; i >= len(newDisks); i-- {
+		if err := removeInstanceDataDisk(ctx, client, auth, instanceId, current[i].(map[string]interface{})); err != nil {
+			return current, err
+		}
+		current = current[:i]
+	}
+
+	for i, rawDisk := range newDisks {
+		newDisk := rawDisk.(map[string]interface{})
+		if i < len(current) {
+			oldDisk := current[i].(map[string]interface{})
+			newDisk["id"] = oldDisk["id"]
+			if newDisk["name"] != oldDisk["name"] || newDisk["capacity"] != oldDisk["capacity"] || newDisk["tier_id"] != oldDisk["tier_id"] {
+				if err := updateInstanceDataDisk(ctx, client, auth, instanceId, subregionId, newDisk); err != nil {
+					return current, err
+				}
+			}
+			current[i] = newDisk
+			continue
+		}
+
+		createCommand := odk.CreateDiskCommand{
+			DiskName:      newDisk["name"].(string),
+			SpaceCapacity: int32(newDisk["capacity"].(int)),
+			TierId:        int32(newDisk["tier_id"].(int)),
+			SubregionId:   subregionId,
+		}
+		diskId, err := createDisk(ctx, client, auth, createCommand)
+		if err != nil {
+			return current, err
+		}
+		newDisk["id"] = int(diskId)
+		current = append(current, newDisk)
+		tflog.Debug(ctx, "calling ODK OVSApi.DisksAttachToInstance", map[string]interface{}{"diskId": diskId, "instanceId": instanceId})
+		if err := attachDiskToInstance(client, auth, diskId, instanceId); err != nil {
+			return current, err
+		}
+	}
+	return current, nil
+}
+
+func updateInstanceDataDisk(ctx context.Context, client odk.APIClient, auth *context.Context, instanceId int32, subregionId int32, dataDisk map[string]interface{}) error {
+	diskId := int32(dataDisk["id"].(int))
+	updateCmd := odk.UpdateDiskCommand{
+		DiskName:        dataDisk["name"].(string),
+		SpaceCapacity:   int32(dataDisk["capacity"].(int)),
+		TierId:          int32(dataDisk["tier_id"].(int)),
+		SubregionId:     subregionId,
+		InstanceIdsList: []int32{instanceId},
+	}
+	tflog.Debug(ctx, "calling ODK OVSApi.DisksPut", map[string]interface{}{"id": diskId})
+	ticket, _, err := client.OVSApi.DisksPut(*auth, diskId, updateCmd)
+	if err != nil {
+		return fmt.Errorf("ODK Error in OVSApi.DisksPut. %s", err)
+	}
+	ticket, err = waitForTicket(client, auth, ticket)
+	if err != nil {
+		return fmt.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+	}
+	if ticket.Status.Id != DICT_TICKET_SUCCEED {
+		return fmt.Errorf("unable to modify OVS %v. Ticket status=%v", diskId, ticket.Status.Id)
+	}
+	return nil
+}
+
+func removeInstanceDataDisk(ctx context.Context, client odk.APIClient, auth *context.Context, instanceId int32, dataDisk map[string]interface{}) error {
+	diskId := int32(dataDisk["id"].(int))
+	if diskId == 0 {
+		// disk was never created
+		return nil
+	}
+	tflog.Debug(ctx, "calling ODK OVSApi.DisksDetachFromInstance", map[string]interface{}{"diskId": diskId, "instanceId": instanceId})
+	if err := detachDiskFromInstance(client, auth, diskId, instanceId); err != nil {
+		return err
+	}
+	if !dataDisk["delete_on_termination"].(bool) {
+		tflog.Info(ctx, fmt.Sprintf("OVS %v detached and kept, delete_on_termination is false", diskId))
+		return nil
+	}
+	return deleteDisk(ctx, client, auth, diskId)
+}
+
+func changeInstanceScsiControllerType(ctx context.Context, client odk.APIClient, auth *context.Context, instanceId int32, scsiControllerTypeId int32) error {
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesChangeType", map[string]interface{}{"id": instanceId, "scsiControllerTypeId": scsiControllerTypeId})
+	ticket, _, err := client.OCIApi.InstancesChangeType(*auth, instanceId, scsiControllerTypeId)
+	if err != nil {
+		return fmt.Errorf("ODK Error in OCIApi.InstancesChangeType. %s", err)
+	}
+	ticket, err = waitForTicket(client, auth, ticket)
+	if err != nil {
+		return fmt.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+	}
+	if ticket.Status.Id != DICT_TICKET_SUCCEED {
+		return fmt.Errorf("unable to change SCSI controller type. Ticket status=%v", ticket.Status.Id)
+	}
+	return nil
+}
+
 func detachDiskFromInstance(client odk.APIClient, auth *context.Context, diskId int32, instanceId int32) error {
 	ticket, resp, err := client.OVSApi.DisksDetachFromInstance(*auth, diskId, instanceId)
 	if err != nil {
@@ -874,27 +1352,29 @@ func detachDiskFromInstance(client odk.APIClient, auth *context.Context, diskId
 	return nil
 }
 
+// attachInstanceToIps attaches IPs one by one, using ticket matching mode of each IP.
 func attachInstanceToIps(client odk.APIClient, auth *context.Context, ips []int32, instanceId int32) error {
-	for _, ip := range ips {
-		ticket, _, err := attachIpById(client, auth, instanceId, ip)
+	for _, ipId := range ips {
+		ip, _, err := getIpAttachmentIp(context.Background(), client, auth, ipId)
 		if err != nil {
-			return fmt.Errorf("can't attach IP with id: %d. Caused by %s", ip, err)
+			return fmt.Errorf("can't attach IP with id: %d. Caused by %s", ipId, err)
 		}
-		if ticket.Status.Id != DICT_TICKET_SUCCEED {
-			return fmt.Errorf("can't attach IP with id %d. Ticket id: %d, Ticket status=%v", ip, ticket.Id, ticket.Status.Id)
+		if err := attachIpToInstance(client, auth, ip, instanceId); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// detachInstanceFromIps detaches IPs one by one, using ticket matching mode of each IP.
 func detachInstanceFromIps(client odk.APIClient, auth *context.Context, ips []int32, instanceId int32) error {
-	for _, ip := range ips {
-		ticket, _, err := detachIpById(client, auth, instanceId, ip)
+	for _, ipId := range ips {
+		ip, _, err := getIpAttachmentIp(context.Background(), client, auth, ipId)
 		if err != nil {
-			return fmt.Errorf("can't detach IP with id: %d. Caused by %s", ip, err)
+			return fmt.Errorf("can't detach IP with id: %d. Caused by %s", ipId, err)
 		}
-		if ticket.Status.Id != DICT_TICKET_SUCCEED {
-			return fmt.Errorf("can't detach IP with id %d. Ticket id: %d, Ticket status=%v", ip, ticket.Id, ticket.Status.Id)
+		if err := detachIpFromInstance(client, auth, ip, instanceId); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -975,47 +1455,163 @@ func detachInstanceFromOpns(client odk.APIClient, auth *context.Context, opnIds
 	return nil
 }
 
-func validateInstanceResource(d *schema.ResourceData) error {
-	publicIps := d.Get("public_ips").(*schema.Set).List()
-	privateIps := d.Get("opn_ids").(*schema.Set).List()
+func resourceInstanceCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.NewValueKnown("system_disk_size") {
+		diskSize := d.Get("system_disk_size").(int)
+		if diskSize < 5 {
+			return fmt.Errorf("system_disk_size must be at least 5 GB, got %d GB", diskSize)
+		}
+		if d.Id() != "" && d.HasChange("system_disk_size") {
+			oldDiskSize, newDiskSize := d.GetChange("system_disk_size")
+			if newDiskSize.(int) < oldDiskSize.(int) {
+				return fmt.Errorf("system disk can be only scaled up, can't shrink it from %d GB to %d GB", oldDiskSize.(int), newDiskSize.(int))
+			}
+		}
+	}
 
-	if len(publicIps) == 0 && len(privateIps) == 0 {
-		return fmt.Errorf("instance must have at least one entry in public_ips or opn_ids specified")
+	if d.Id() != "" && d.HasChange("data_disk") {
+		oldDataDisks, newDataDisks := d.GetChange("data_disk")
+		oldList := oldDataDisks.([]interface{})
+		for i, rawDisk := range newDataDisks.([]interface{}) {
+			if i >= len(oldList) {
+				break
+			}
+			oldCapacity := oldList[i].(map[string]interface{})["capacity"].(int)
+			newCapacity := rawDisk.(map[string]interface{})["capacity"].(int)
+			// zero means capacity is not known yet
+			if newCapacity != 0 && newCapacity < oldCapacity {
+				return fmt.Errorf("data_disk.%d can be only scaled up, can't shrink it from %d GB to %d GB", i, oldCapacity, newCapacity)
+			}
+		}
+	}
+
+	// Oktawave API can't reinstall instance, so template change would never be applied
+	if d.Id() != "" && d.HasChange("template_id") {
+		oldTemplateId, newTemplateId := d.GetChange("template_id")
+		return fmt.Errorf("template_id can't be changed from %d to %d in place, Oktawave API doesn't support reinstalling instances. Use terraform apply -replace to recreate instance", oldTemplateId.(int), newTemplateId.(int))
+	}
+
+	if d.NewValueKnown("authorization_method_id") && d.NewValueKnown("ssh_keys_ids") {
+		if d.Get("authorization_method_id").(int) == DICT_LOGIN_TYPE_SSH_KEYS && d.Get("ssh_keys_ids").(*schema.Set).Len() == 0 {
+			return fmt.Errorf("ssh_keys_ids can't be empty when authorization_method_id is set to ssh keys (%d)", DICT_LOGIN_TYPE_SSH_KEYS)
+		}
+	}
+
+	if d.NewValueKnown("without_public_ip") && d.NewValueKnown("opn_ids") {
+		if d.Get("without_public_ip").(bool) && d.Get("opn_ids").(*schema.Set).Len() == 0 {
+			return fmt.Errorf("instance created with without_public_ip must have at least one entry in opn_ids")
+		}
+	}
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	if (d.Id() == "" || d.HasChange("subregion_id")) && d.NewValueKnown("subregion_id") {
+		if err := checkSubregionIsActive(ctx, client, auth, int32(d.Get("subregion_id").(int))); err != nil {
+			return err
+		}
+	}
+
+	if (d.Id() == "" || d.HasChange("type_id")) && d.NewValueKnown("type_id") && d.NewValueKnown("template_id") {
+		if err := checkInstanceTypeMeetsTemplateMinimum(ctx, client, auth, int32(d.Get("type_id").(int)), int32(d.Get("template_id").(int))); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func getInstanceTemplate(client odk.APIClient, auth *context.Context, instanceId int32) (template *odk.Template, err error) {
-	// if template id is not set, call api for template id
-	tmpl, resp, err := client.OCIApi.InstancesGetTemplateByBaseVirtualMachineId(*auth, instanceId, nil)
+func checkSubregionIsActive(ctx context.Context, client odk.APIClient, auth *context.Context, subregionId int32) error {
+	tflog.Debug(ctx, "calling ODK SubregionsApi.SubregionsGet_1")
+	subregion, resp, err := client.SubregionsApi.SubregionsGet_1(*auth, subregionId, nil)
 	if err != nil {
-		// if api returns error 404 - no template found, ok
-		if resp.StatusCode == 404 {
-			return nil, nil
-		}
-		// if api returns error other than 404 - print error and fail
-		if resp.StatusCode != 404 {
-			return nil, fmt.Errorf("failed to check template data for instance %d. Caused by: %s", instanceId, err)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("subregion %v not found", subregionId)
 		}
+		return fmt.Errorf("ODK Error in SubregionsApi.SubregionsGet_1. %s", err)
 	}
-	return &tmpl, nil
+	if !subregion.IsActive {
+		return fmt.Errorf("subregion %v (%s) is not active", subregion.Id, subregion.Name)
+	}
+	return nil
 }
 
-func isConvertedToTemplate(client odk.APIClient, auth *context.Context, d *schema.ResourceData, instanceId int32) (isConverted bool, templateId int32, err error) {
-	tmplId, isTemplateIdSet := d.GetOk("converted_to_template_id")
-	if isTemplateIdSet {
-		return true, int32(tmplId.(int)), nil
+func checkInstanceTypeMeetsTemplateMinimum(ctx context.Context, client odk.APIClient, auth *context.Context, typeId int32, templateId int32) error {
+	tflog.Debug(ctx, "calling ODK OCITemplatesApi.TemplatesGet_1")
+	template, resp, err := client.OCITemplatesApi.TemplatesGet_1(*auth, templateId, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("template %v not found", templateId)
+		}
+		return fmt.Errorf("ODK Error in OCITemplatesApi.TemplatesGet_1. %s", err)
+	}
+	if template.MinimumInstanceType == nil || template.MinimumInstanceType.Id == typeId {
+		return nil
 	}
 
-	template, err := getInstanceTemplate(client, auth, int32(instanceId))
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesGetInstancesTypes")
+	params := map[string]interface{}{
+		"pageSize": int32(math.MaxInt16),
+	}
+	types, _, err := client.OCIApi.InstancesGetInstancesTypes(*auth, params)
 	if err != nil {
-		return false, 0, err
+		return fmt.Errorf("ODK Error in OCIApi.InstancesGetInstancesTypes. %s", err)
+	}
+	var instanceType, minimumType *odk.InstanceType
+	for i, item := range types.Items {
+		if item.Id == typeId {
+			instanceType = &types.Items[i]
+		}
+		if item.Id == template.MinimumInstanceType.Id {
+			minimumType = &types.Items[i]
+		}
+	}
+	if instanceType == nil {
+		return fmt.Errorf("instance type %v not found", typeId)
+	}
+	if minimumType == nil {
+		// minimum type is not offered anymore, api will decide
+		return nil
 	}
+	if instanceType.Cpu < minimumType.Cpu || instanceType.Ram < minimumType.Ram {
+		return fmt.Errorf("instance type %v (%s) is below minimum type %v (%s) required by template %v", instanceType.Id, instanceType.Name, minimumType.Id, minimumType.Name, templateId)
+	}
+	return nil
+}
 
-	if template == nil {
-		return false, 0, nil
+func validateInstanceResource(d *schema.ResourceData) error {
+	publicIps := d.Get("public_ips").(*schema.Set).List()
+	privateIps := d.Get("opn_ids").(*schema.Set).List()
+
+	if len(publicIps) == 0 && len(privateIps) == 0 {
+		return fmt.Errorf("instance must have at least one entry in public_ips or opn_ids specified")
 	}
 
-	return true, template.Id, nil
+	return nil
+}
+
+// getInstanceTemplate returns template created from given instance, or nil if instance was not converted.
+func getInstanceTemplate(ctx context.Context, client odk.APIClient, auth *context.Context, instanceId int32) (*odk.Template, error) {
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesGetTemplateByBaseVirtualMachineId", map[string]interface{}{"id": instanceId})
+	template, resp, err := client.OCIApi.InstancesGetTemplateByBaseVirtualMachineId(*auth, instanceId, nil)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to check template data for instance %d. Caused by: %s", instanceId, err)
+	}
+	return &template, nil
+}
+
+// dropConvertedInstance removes instance consumed by conversion to template from state. It is never deleted.
+func dropConvertedInstance(ctx context.Context, d *schema.ResourceData, instanceId int32, templateId int32) diag.Diagnostics {
+	tflog.Warn(ctx, fmt.Sprintf("OCI %v was converted to template %v, removing it from state", instanceId, templateId))
+	d.SetId("")
+	return diag.Diagnostics{
+		{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Instance %v was converted to template %v", instanceId, templateId),
+			Detail:   "Instance ceased to exist when it was converted to template and was removed from state. Remove its definition, otherwise a new instance will be created.",
+		},
+	}
 }
@@ -0,0 +1,371 @@
+package oktawave
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/oktawave-code/odk"
+)
+
+func resourceInstanceClone() *schema.Resource {
+	instance := resourceInstance()
+	cloneSchema := instance.Schema
+
+	// Placement defaults to the one of source instance
+	for _, key := range []string{"subregion_id", "system_disk_class_id", "type_id", "system_disk_size", "opn_ids", "disks_ids"} {
+		cloneSchema[key].Required = false
+		cloneSchema[key].Optional = true
+		cloneSchema[key].Computed = true
+		cloneSchema[key].Default = nil
+	}
+	cloneSchema["subregion_id"].ForceNew = true
+	cloneSchema["subregion_id"].Description = "ID from subregions resource. Defaults to subregion of source instance."
+	cloneSchema["disks_ids"].Description = "Ids of connected disks, including disks copied from source instance."
+
+	// Inherited from source instance, can't be set for clone
+	for _, key := range []string{"template_id", "authorization_method_id", "ssh_keys_ids", "without_public_ip", "init_script"} {
+		inherited := cloneSchema[key]
+		cloneSchema[key] = &schema.Schema{
+			Type:        inherited.Type,
+			Elem:        inherited.Elem,
+			Computed:    true,
+			Sensitive:   inherited.Sensitive,
+			Description: inherited.Description,
+		}
+	}
+	delete(cloneSchema, "user_data")
+	delete(cloneSchema, "user_data_base64")
+	delete(cloneSchema, "user_data_gzip")
+	delete(cloneSchema, "converted_to_template_id")
+
+	cloneSchema["source_instance_id"] = &schema.Schema{
+		Type:        schema.TypeInt,
+		Required:    true,
+		ForceNew:    true,
+		Description: "Id of instance which is cloned. Source instance is left intact. It can't be read from API, so it is passed in import id.",
+	}
+	cloneSchema["clone_type_id"] = &schema.Schema{
+		Type:         schema.TypeInt,
+		Required:     true,
+		ForceNew:     true,
+		ValidateFunc: validation.IntAtLeast(1),
+		Description:  "Clone type passed as CloneType of ODK clone operation. ODK doesn't publish its dictionary, so value is checked by API. It can't be read from API, so it is passed in import id.",
+	}
+	cloneSchema["power_on"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		ForceNew:    true,
+		Default:     true,
+		Description: "Power on clone after it is created.",
+	}
+	cloneSchema["cloned_disks_ids"] = &schema.Schema{
+		Type:     schema.TypeSet,
+		Computed: true,
+		Elem: &schema.Schema{
+			Type: schema.TypeInt,
+		},
+		Description: "Ids of additional disks copied from source instance. They are deleted together with clone. They can't be told apart from other attached disks, so they are passed in import id.",
+	}
+
+	return &schema.Resource{
+		CreateContext: resourceInstanceCloneCreate,
+		ReadContext:   resourceInstanceRead,
+		UpdateContext: resourceInstanceUpdate,
+		DeleteContext: resourceInstanceCloneDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceInstanceCloneImport,
+		},
+		CustomizeDiff: resourceInstanceCustomizeDiff,
+		Schema:        cloneSchema,
+		Timeouts:      instance.Timeouts,
+		Description:   "Copy of existing instance created with ODK clone operation. After creation it is managed like oktawave_instance. Import id has <clone_id>/<source_instance_id>/<clone_type_id>[/<cloned_disk_id>,...] format.",
+	}
+}
+
+func resourceInstanceCloneCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "cloning instance")
+	deadline := time.Now().Add(d.Timeout(schema.TimeoutCreate))
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	sourceId := int32(d.Get("source_instance_id").(int))
+	name := d.Get("name").(string)
+
+	// clone ticket doesn't tell clone id reliably, clone is found by its name
+	sameNameIds, err := findInstancesByName(ctx, client, auth, name)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	cloneCommand := odk.CloneInstanceCommand{
+		CloneName: name,
+		CloneType: int32(d.Get("clone_type_id").(int)),
+		PowerOn:   d.Get("power_on").(bool),
+	}
+	if subregionId, ok := d.GetOk("subregion_id"); ok {
+		cloneCommand.SubregionId = int32(subregionId.(int))
+	}
+
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesClone", map[string]interface{}{"id": sourceId, "name": name})
+	ticket, _, err := client.OCIApi.InstancesClone(*auth, sourceId, cloneCommand)
+	if err != nil {
+		return diag.Errorf("ODK Error in OCIApi.InstancesClone. %s", err)
+	}
+
+	cloneTicket, err := waitForTicket(client, auth, ticket)
+	if err != nil {
+		return diag.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+	}
+	if cloneTicket.Status.Id != DICT_TICKET_SUCCEED {
+		return diag.Errorf("Unable to clone instance %v. Ticket status=%v", sourceId, cloneTicket.Status.Id)
+	}
+
+	nameIds, err := findInstancesByName(ctx, client, auth, name)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	newIds := calcListAMinusListB(nameIds, append(sameNameIds, sourceId))
+	if len(newIds) != 1 {
+		return diag.Errorf("Instance %v was cloned, but clone named %q can't be identified. Found %v new instances with this name", sourceId, name, len(newIds))
+	}
+	cloneId := newIds[0]
+
+	tflog.Info(ctx, fmt.Sprintf("successfully cloned OCI %v. id=%v", sourceId, cloneId))
+	d.SetId(strconv.Itoa(int(cloneId)))
+
+	// remember disks copied from source, so they are deleted with clone
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesGetDisks", map[string]interface{}{"id": cloneId})
+	params := map[string]interface{}{
+		"pageSize": int32(math.MaxInt16),
+	}
+	disks, _, err := client.OCIApi.InstancesGetDisks(*auth, cloneId, params)
+	if err != nil {
+		return diag.Errorf("ODK Error in OCIApi.InstancesGetDisks. %s", err)
+	}
+	clonedDisks := make([]int32, 0)
+	for _, disk := range disks.Items {
+		for _, connection := range disk.Connections {
+			if connection.Instance.Id == cloneId && !connection.IsSystemDisk {
+				clonedDisks = append(clonedDisks, disk.Id)
+			}
+		}
+	}
+	if d.Set("cloned_disks_ids", clonedDisks) != nil {
+		return diag.Errorf("Can't store cloned disks list")
+	}
+
+	if err := placeInstanceClone(ctx, client, auth, d, cloneId, clonedDisks); err != nil {
+		return diag.Errorf("Instance %v was cloned to %v, but it can't be configured. %s", sourceId, cloneId, err)
+	}
+
+	if dataDisks := d.Get("data_disk").([]interface{}); len(dataDisks) > 0 {
+		tflog.Debug(ctx, "calling ODK OCIApi.InstancesGet_2", map[string]interface{}{"id": cloneId})
+		clone, _, err := client.OCIApi.InstancesGet_2(*auth, cloneId, nil)
+		if err != nil {
+			return diag.Errorf("ODK Error in OCIApi.InstancesGet_2. %s", err)
+		}
+		current, err := applyInstanceDataDisks(ctx, client, auth, cloneId, clone.Subregion.Id, nil, dataDisks)
+		if d.Set("data_disk", current) != nil {
+			return diag.Errorf("Can't store data disks")
+		}
+		if err != nil {
+			return diag.Errorf("Creating data disks failed. %s", err)
+		}
+	}
+
+	if err := waitForInstanceReadiness(ctx, client, auth, d, cloneId, deadline); err != nil {
+		return diag.Errorf("Instance %v was cloned, but it is not ready. %s", cloneId, err)
+	}
+
+	// clone got its IPs, cached listing is outdated
+	m.(*ClientConfig).ipCache.invalidate()
+	return resourceInstanceRead(ctx, d, m)
+}
+
+// resourceInstanceCloneImport reads arguments which can't be read from API from import id
+// in <clone_id>/<source_instance_id>/<clone_type_id>[/<cloned_disk_id>,...] format.
+func resourceInstanceCloneImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.Split(d.Id(), "/")
+	if len(parts) != 3 && len(parts) != 4 {
+		return nil, fmt.Errorf("invalid instance clone import id %q, expected <clone_id>/<source_instance_id>/<clone_type_id>[/<cloned_disk_id>,...]", d.Id())
+	}
+	ids := make([]int, 0, len(parts))
+	for _, part := range parts[:3] {
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid instance clone import id %q. %s", d.Id(), err)
+		}
+		ids = append(ids, id)
+	}
+	clonedDisks := make([]int, 0)
+	if len(parts) == 4 && parts[3] != "" {
+		for _, part := range strings.Split(parts[3], ",") {
+			diskId, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cloned disk id in instance clone import id %q. %s", d.Id(), err)
+			}
+			clonedDisks = append(clonedDisks, diskId)
+		}
+	}
+
+	d.SetId(strconv.Itoa(ids[0]))
+	if d.Set("source_instance_id", ids[1]) != nil {
+		return nil, fmt.Errorf("can't set source instance id")
+	}
+	if d.Set("clone_type_id", ids[2]) != nil {
+		return nil, fmt.Errorf("can't set clone type id")
+	}
+	if d.Set("cloned_disks_ids", clonedDisks) != nil {
+		return nil, fmt.Errorf("can't set cloned disks ids")
+	}
+	if d.Set("power_on", true) != nil {
+		return nil, fmt.Errorf("can't set power on")
+	}
+	return importStatePassthroughWithDeletionProtection(ctx, d, m)
+}
+
+func resourceInstanceCloneDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "deleting instance clone")
+
+	if diags := checkDeletionProtection(d); diags != nil {
+		return diags
+	}
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	instanceId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.Errorf("Invalid OCI id: %v %s", d.Id(), err)
+	}
+
+	for _, rawDisk := range d.Get("data_disk").([]interface{}) {
+		if err := removeInstanceDataDisk(ctx, client, auth, int32(instanceId), rawDisk.(map[string]interface{})); err != nil {
+			return diag.Errorf("Removing data disks failed. %s", err)
+		}
+	}
+
+	// disks attached later are managed elsewhere, only copies made by clone operation are deleted
+	disksIds := castToInt32(d.Get("disks_ids").(*schema.Set).List())
+	clonedDisksIds := castToInt32(d.Get("cloned_disks_ids").(*schema.Set).List())
+	for _, diskId := range calcListAMinusListB(disksIds, clonedDisksIds) {
+		if err := detachDiskFromInstance(client, auth, diskId, int32(instanceId)); err != nil {
+			return diag.Errorf("Detaching disks failed. %s", err)
+		}
+	}
+
+	err = deleteInstance(ctx, client, auth, int32(instanceId), true)
+	m.(*ClientConfig).ipCache.invalidate()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// placeInstanceClone applies type, OPNs, IPs and disks from definition to clone, which inherited them from source.
+func placeInstanceClone(ctx context.Context, client odk.APIClient, auth *context.Context, d *schema.ResourceData, cloneId int32, clonedDisks []int32) error {
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesGet_2", map[string]interface{}{"id": cloneId})
+	clone, _, err := client.OCIApi.InstancesGet_2(*auth, cloneId, nil)
+	if err != nil {
+		return fmt.Errorf("ODK Error in OCIApi.InstancesGet_2. %s", err)
+	}
+
+	if typeId, ok := d.GetOk("type_id"); ok && (clone.Type_ == nil || clone.Type_.Id != int32(typeId.(int))) {
+		tflog.Debug(ctx, "calling ODK OCIApi.InstancesChangeType")
+		ticket, _, err := client.OCIApi.InstancesChangeType_1(*auth, cloneId, int32(typeId.(int)))
+		if err != nil {
+			return fmt.Errorf("ODK Error in OCIApi.InstancesChangeType. %s", err)
+		}
+		ticket, err = waitForTicket(client, auth, ticket)
+		if err != nil {
+			return fmt.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+		}
+		if ticket.Status.Id != DICT_TICKET_SUCCEED {
+			return fmt.Errorf("unable to change instance type. Ticket status=%v", ticket.Status.Id)
+		}
+	}
+
+	if scsiControllerTypeId, ok := d.GetOk("scsi_controller_type_id"); ok && (clone.ScsiControllerType == nil || clone.ScsiControllerType.Id != int32(scsiControllerTypeId.(int))) {
+		if err := changeInstanceScsiControllerType(ctx, client, auth, cloneId, int32(scsiControllerTypeId.(int))); err != nil {
+			return err
+		}
+	}
+
+	if opnIdSet, ok := d.GetOk("opn_ids"); ok {
+		_, currentOpnIds, err := getOpnsData(client, *auth, cloneId)
+		if err != nil {
+			return err
+		}
+		current := castIntToInt32(currentOpnIds)
+		wanted := castToInt32(opnIdSet.(*schema.Set).List())
+		if err := attachInstanceToOpns(client, auth, calcListAMinusListB(wanted, current), cloneId); err != nil {
+			return fmt.Errorf("attaching OPNs failed. %s", err)
+		}
+		if err := detachInstanceFromOpns(client, auth, calcListAMinusListB(current, wanted), cloneId); err != nil {
+			return fmt.Errorf("detaching OPNs failed. %s", err)
+		}
+	}
+
+	if ipIdSet, ok := d.GetOk("public_ips"); ok {
+		tflog.Debug(ctx, "calling ODK FloatingIPsApi.FloatingIpsGetIps")
+		params := map[string]interface{}{
+			"instanceId": cloneId,
+			"pageSize":   int32(math.MaxInt16),
+		}
+		ips, _, err := client.FloatingIPsApi.FloatingIpsGetIps(*auth, params)
+		if err != nil {
+			return fmt.Errorf("ODK Error in FloatingIPsApi.FloatingIpsGetIps. %s", err)
+		}
+		current := make([]int32, 0)
+		for _, ip := range ips.Items {
+			current = append(current, ip.Id)
+		}
+		wanted := castToInt32(ipIdSet.(*schema.Set).List())
+		if err := attachInstanceToIps(client, auth, calcListAMinusListB(wanted, current), cloneId); err != nil {
+			return fmt.Errorf("attaching IPs failed. %s", err)
+		}
+		if err := detachInstanceFromIps(client, auth, calcListAMinusListB(current, wanted), cloneId); err != nil {
+			return fmt.Errorf("detaching IPs failed. %s", err)
+		}
+	}
+
+	if disksIdSet, ok := d.GetOk("disks_ids"); ok {
+		wanted := castToInt32(disksIdSet.(*schema.Set).List())
+		for _, diskId := range calcListAMinusListB(wanted, clonedDisks) {
+			if err := attachDiskToInstance(client, auth, diskId, cloneId); err != nil {
+				return fmt.Errorf("attaching disks failed. %s", err)
+			}
+		}
+	}
+	return nil
+}
+
+func findInstancesByName(ctx context.Context, client odk.APIClient, auth *context.Context, name string) ([]int32, error) {
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesGet")
+	params := map[string]interface{}{
+		"query":    name,
+		"pageSize": int32(math.MaxInt16),
+	}
+	list, _, err := client.OCIApi.InstancesGet(*auth, params)
+	if err != nil {
+		return nil, fmt.Errorf("ODK Error in OCIApi.InstancesGet. %s", err)
+	}
+	ids := make([]int32, 0)
+	for _, instance := range list.Items {
+		if instance.Name == name {
+			ids = append(ids, instance.Id)
+		}
+	}
+	return ids, nil
+}
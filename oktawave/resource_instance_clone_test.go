@@ -0,0 +1,77 @@
+package oktawave
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/oktawave-code/odk"
+)
+
+func TestAccOktawaveInstanceClone_Basic(t *testing.T) {
+	var source, clone odk.Instance
+	cloneConfig := func(typeId int) string {
+		return fmt.Sprintf(`
+resource "oktawave_ip" "test-source-ip" {
+	subregion_id = 1
+}
+
+resource "oktawave_ip" "test-clone-ip" {
+	subregion_id = 1
+}
+
+resource "oktawave_opn" "test-opn" {
+	name = "test-clone-opn"
+}
+
+resource "oktawave_instance" "test-source" {
+	name = "test-clone-source"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	public_ips = [oktawave_ip.test-source-ip.id]
+}
+
+resource "oktawave_instance_clone" "test-clone" {
+	source_instance_id = oktawave_instance.test-source.id
+	clone_type_id = 1
+	name = "test-clone"
+	type_id = %d
+	public_ips = [oktawave_ip.test-clone-ip.id]
+	opn_ids = [oktawave_opn.test-opn.id]
+}
+`, typeId)
+	}
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: cloneConfig(1047),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckInstanceExists("oktawave_instance.test-source", &source),
+					testAccCheckInstanceExists("oktawave_instance_clone.test-clone", &clone),
+					resource.TestCheckResourceAttr("oktawave_instance_clone.test-clone", "name", "test-clone"),
+					resource.TestCheckResourceAttr("oktawave_instance_clone.test-clone", "subregion_id", "1"),
+					resource.TestCheckResourceAttr("oktawave_instance_clone.test-clone", "type_id", "1047"),
+					resource.TestCheckResourceAttrPair("oktawave_instance_clone.test-clone", "template_id", "oktawave_instance.test-source", "template_id"),
+					resource.TestCheckResourceAttr("oktawave_instance_clone.test-clone", "public_ips.#", "1"),
+					resource.TestCheckResourceAttr("oktawave_instance_clone.test-clone", "opn_ids.#", "1"),
+					resource.TestCheckResourceAttr("oktawave_instance_clone.test-clone", "cloned_disks_ids.#", "0"),
+				),
+			},
+			{
+				// clone is managed like regular instance
+				Config: cloneConfig(1049),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckInstanceExists("oktawave_instance_clone.test-clone", &clone),
+					resource.TestCheckResourceAttr("oktawave_instance_clone.test-clone", "type_id", "1049"),
+					resource.TestCheckResourceAttr("oktawave_instance.test-source", "type_id", "1047"),
+				),
+			},
+		},
+	})
+}
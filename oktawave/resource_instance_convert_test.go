@@ -0,0 +1,73 @@
+package oktawave
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/oktawave-code/odk"
+)
+
+func TestResourceInstanceReadConvertedToTemplate(t *testing.T) {
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/instances/5":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodGet && r.URL.Path == "/instances/5/template":
+			testWriteJson(t, w, odk.Template{Id: 77})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	d := schema.TestResourceDataRaw(t, resourceInstance().Schema, map[string]interface{}{})
+	d.SetId("5")
+
+	diags := resourceInstanceRead(context.Background(), d, config)
+	if len(diags) != 1 || diags[0].Severity != diag.Warning {
+		t.Fatalf("expected single warning, got %v", diags)
+	}
+	if d.Id() != "" {
+		t.Errorf("expected instance to be removed from state, id is %q", d.Id())
+	}
+}
+
+func TestResourceInstanceReadLegacyConvertedState(t *testing.T) {
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	d := schema.TestResourceDataRaw(t, resourceInstance().Schema, map[string]interface{}{
+		"converted_to_template_id": 77,
+	})
+	d.SetId("5")
+
+	diags := resourceInstanceRead(context.Background(), d, config)
+	if diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	if d.Id() != "" {
+		t.Errorf("expected instance to be removed from state, id is %q", d.Id())
+	}
+}
+
+func TestResourceInstanceReadMissing(t *testing.T) {
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	d := schema.TestResourceDataRaw(t, resourceInstance().Schema, map[string]interface{}{})
+	d.SetId("5")
+
+	diags := resourceInstanceRead(context.Background(), d, config)
+	if !diags.HasError() {
+		t.Error("expected not found error")
+	}
+	if d.Id() != "" {
+		t.Errorf("expected instance to be removed from state, id is %q", d.Id())
+	}
+}
@@ -0,0 +1,116 @@
+package oktawave
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/oktawave-code/odk"
+)
+
+func testMockInstance(id int32) odk.Instance {
+	item := &odk.DictionaryItem{Id: 1}
+	return odk.Instance{
+		Id:                 id,
+		Name:               "test-instance",
+		CreationUser:       &odk.UserResource{Id: 1},
+		Template:           &odk.BaseResource{Id: 1021},
+		Subregion:          &odk.BaseResource{Id: 1},
+		Type_:              item,
+		Status:             item,
+		SystemCategory:     item,
+		AutoscalingType:    item,
+		VmWareToolsStatus:  item,
+		MonitStatus:        item,
+		TemplateType:       item,
+		PaymentType:        item,
+		ScsiControllerType: item,
+	}
+}
+
+func TestResourceInstanceReadSharesIpListing(t *testing.T) {
+	var mutex sync.Mutex
+	calls := make(map[string]int)
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		calls[r.URL.Path]++
+		mutex.Unlock()
+
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case r.URL.Path == "/floating_ips":
+			if r.URL.Query().Get("instanceId") != "" {
+				t.Errorf("expected account-wide ip listing, got %s", r.URL.RawQuery)
+			}
+			testWriteJson(t, w, odk.ApiCollectionIp{Items: []odk.Ip{
+				{Id: 11, MacAddress: "00:00:00:00:00:11", Instance: &odk.BaseResource{Id: 5}},
+				{Id: 12, MacAddress: "00:00:00:00:00:12", Instance: &odk.BaseResource{Id: 5}},
+				{Id: 21, MacAddress: "00:00:00:00:00:21", Instance: &odk.BaseResource{Id: 6}},
+				{Id: 31, MacAddress: "00:00:00:00:00:31"},
+			}})
+		case len(parts) == 2 && parts[0] == "instances":
+			id := int32(5)
+			if parts[1] == "6" {
+				id = 6
+			}
+			testWriteJson(t, w, testMockInstance(id))
+		case len(parts) == 3 && parts[2] == "disks":
+			id := int32(5)
+			if parts[1] == "6" {
+				id = 6
+			}
+			testWriteJson(t, w, odk.ApiCollectionDisk{Items: []odk.Disk{{
+				Id:            100 + id,
+				SpaceCapacity: 5,
+				Tier:          &odk.DictionaryItem{Id: DICT_DISK_TIER_1},
+				Connections:   []odk.DiskConnection{{Instance: &odk.BaseResource{Id: id}, IsSystemDisk: true}},
+			}}})
+		case len(parts) == 3 && parts[2] == "opns":
+			testWriteJson(t, w, odk.ApiCollectionOpn{})
+		case len(parts) == 3 && parts[2] == "ssh_keys":
+			testWriteJson(t, w, odk.ApiCollectionInstanceSshKey{})
+		case len(parts) == 3 && parts[2] == "init_script":
+			testWriteJson(t, w, "")
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	expectedIps := map[string][]int{"5": {11, 12}, "6": {21}}
+	for _, id := range []string{"5", "6", "5"} {
+		d := schema.TestResourceDataRaw(t, resourceInstance().Schema, map[string]interface{}{})
+		d.SetId(id)
+		if diags := resourceInstanceRead(context.Background(), d, config); diags.HasError() {
+			t.Fatalf("unexpected error %v", diags)
+		}
+		publicIps := d.Get("public_ips").(*schema.Set)
+		if publicIps.Len() != len(expectedIps[id]) {
+			t.Errorf("instance %s: expected ips %v, got %v", id, expectedIps[id], publicIps.List())
+		}
+		for _, ipId := range expectedIps[id] {
+			if !publicIps.Contains(ipId) {
+				t.Errorf("instance %s: ip %v is missing", id, ipId)
+			}
+		}
+	}
+	if calls["/floating_ips"] != 1 {
+		t.Errorf("expected single ip listing for 3 reads, got %v", calls["/floating_ips"])
+	}
+	if calls["/instances/5/disks"] != 2 || calls["/instances/6/disks"] != 1 {
+		t.Errorf("expected one disks listing per read, got %v", calls)
+	}
+
+	config.ipCache.invalidate()
+	d := schema.TestResourceDataRaw(t, resourceInstance().Schema, map[string]interface{}{})
+	d.SetId("6")
+	if diags := resourceInstanceRead(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	if calls["/floating_ips"] != 2 {
+		t.Errorf("expected ip listing to be reloaded after invalidation, got %v listings", calls["/floating_ips"])
+	}
+}
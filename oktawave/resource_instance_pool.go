@@ -0,0 +1,541 @@
+package oktawave
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/oktawave-code/odk"
+)
+
+func resourceInstancePool() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceInstancePoolCreate,
+		ReadContext:   resourceInstancePoolRead,
+		UpdateContext: resourceInstancePoolUpdate,
+		DeleteContext: resourceInstancePoolDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceInstancePoolImport,
+		},
+		Schema: map[string]*schema.Schema{
+			// Required
+			"name_prefix": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Prefix of pool members names. Members are named <prefix>-<n>. It is also used as pool id.",
+			},
+			"instances_count": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Number of instances in pool. Pool is scaled in and out when this value changes.",
+			},
+			"subregion_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "ID from subregions resource.",
+			},
+			"system_disk_class_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Defines disk performance class. Value from dictionary #17",
+			},
+			"template_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Defines which image will be used for instances initialization. ID from templates resource",
+			},
+			"type_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Defines vCPU and RAM for instances. Value from dictionary #12",
+			},
+			// Optional
+			"authorization_method_id": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     DICT_LOGIN_TYPE_USER_AND_PASS,
+				Description: "Two authorization methods are available - login/password or ssh-keys. Value from dictionary #159",
+			},
+			"ssh_keys_ids": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				ForceNew: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeInt,
+				},
+				Description: "List of ssh keys injected to instances during initialization.",
+			},
+			"opn_ids": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				ForceNew: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeInt,
+				},
+				Description: "List of OPNs pool members are in.",
+			},
+			"system_disk_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      5,
+				ValidateFunc: validation.IntAtLeast(5),
+				Description:  "Disk size in GB. At least 5 GB.",
+			},
+			"without_public_ip": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Creates pool members without public IP. In this case opn_ids must not be empty.",
+			},
+			"init_script": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				Sensitive:     true,
+				ConflictsWith: []string{"user_data", "user_data_base64"},
+				Description:   "Must be base64 encoded. This script will be invoked during instances initialization.",
+			},
+			"user_data": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"init_script", "user_data_base64"},
+				StateFunc:     userDataStateFunc,
+				Description:   "Plain text script invoked during instances initialization. Only its hash is stored in state.",
+			},
+			"user_data_base64": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"init_script", "user_data"},
+				StateFunc:     userDataStateFunc,
+				ValidateFunc:  validation.StringIsBase64,
+				Description:   "Base64 encoded script invoked during instances initialization. Only its hash is stored in state.",
+			},
+			"user_data_gzip": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     false,
+				Description: "Compress user_data or user_data_base64 content with gzip before sending.",
+			},
+			// Computed
+			"instance_ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeInt,
+				},
+				Description: "Ids of pool members, ordered by id.",
+			},
+			"ip_addresses": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Main ip addresses of pool members, in instance_ids order.",
+			},
+			"private_ip_addresses": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Private ip addresses of pool members, in instance_ids order.",
+			},
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(45 * time.Minute),
+		},
+		Description: "Pool of identical instances created with a single ticket and scaled by changing instances_count. Only instances created by the pool are tracked in instance_ids and removed on scale in.",
+	}
+}
+
+func resourceInstancePoolCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "creating instance pool")
+
+	if d.Get("without_public_ip").(bool) && d.Get("opn_ids").(*schema.Set).Len() == 0 {
+		return diag.Errorf("Pool created with without_public_ip must have at least one entry in opn_ids")
+	}
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	namePrefix := d.Get("name_prefix").(string)
+	d.SetId(namePrefix)
+
+	members, err := createInstancePoolMembers(ctx, client, auth, d, nil, int32(d.Get("instances_count").(int)))
+	m.(*ClientConfig).ipCache.invalidate()
+	if d.Set("instance_ids", members) != nil {
+		return diag.Errorf("Can't store pool members")
+	}
+	if err != nil {
+		if len(members) == 0 {
+			d.SetId("")
+		}
+		return diag.Errorf("Unable to create instance pool. %s", err)
+	}
+
+	return resourceInstancePoolRead(ctx, d, m)
+}
+
+func resourceInstancePoolRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "reading instance pool")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	memberIds := castToInt32(d.Get("instance_ids").([]interface{}))
+	var instances []odk.Instance
+	for _, memberId := range memberIds {
+		tflog.Debug(ctx, "calling ODK OCIApi.InstancesGet", map[string]interface{}{"id": memberId})
+		instance, resp, err := client.OCIApi.InstancesGet_2(*auth, memberId, nil)
+		if err != nil {
+			if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) { // api returns 403 on missing instance
+				tflog.Warn(ctx, fmt.Sprintf("Pool member %v no longer exists", memberId))
+				continue
+			}
+			return diag.Errorf("Error while retrieving pool member %v: %s", memberId, err)
+		}
+		instances = append(instances, instance)
+	}
+
+	return loadInstancePoolData(ctx, d, m, instances)
+}
+
+func resourceInstancePoolUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "updating instance pool")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	if d.HasChange("instances_count") {
+		members := castToInt32(d.Get("instance_ids").([]interface{}))
+		wanted := d.Get("instances_count").(int)
+		tflog.Info(ctx, fmt.Sprintf("pool size change detected (%d -> %d)", len(members), wanted))
+
+		if wanted > len(members) {
+			members, err := createInstancePoolMembers(ctx, client, auth, d, members, int32(wanted-len(members)))
+			m.(*ClientConfig).ipCache.invalidate()
+			if d.Set("instance_ids", members) != nil {
+				return diag.Errorf("Can't store pool members")
+			}
+			if err != nil {
+				return diag.Errorf("Unable to scale out instance pool. %s", err)
+			}
+		}
+
+		if wanted < len(members) {
+			// newest instances are removed first
+			for len(members) > wanted {
+				last := members[len(members)-1]
+				err := deleteInstance(ctx, client, auth, last, false)
+				m.(*ClientConfig).ipCache.invalidate()
+				if err != nil {
+					return diag.Errorf("Unable to scale in instance pool. %s", err)
+				}
+				members = members[:len(members)-1]
+				if d.Set("instance_ids", members) != nil {
+					return diag.Errorf("Can't store pool members")
+				}
+			}
+		}
+	}
+
+	return resourceInstancePoolRead(ctx, d, m)
+}
+
+func resourceInstancePoolDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "deleting instance pool")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	members := castToInt32(d.Get("instance_ids").([]interface{}))
+	for len(members) > 0 {
+		last := members[len(members)-1]
+		err := deleteInstance(ctx, client, auth, last, false)
+		m.(*ClientConfig).ipCache.invalidate()
+		if err != nil {
+			return diag.Errorf("Unable to delete instance pool. %s", err)
+		}
+		members = members[:len(members)-1]
+		if d.Set("instance_ids", members) != nil {
+			return diag.Errorf("Can't store pool members")
+		}
+	}
+
+	d.SetId("")
+	return nil
+}
+
+func resourceInstancePoolImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	// pool is identified by name prefix, members are found by <prefix>-<n> names
+	members, err := findInstancePoolMembers(ctx, client, auth, d.Id())
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("no instances named %s-<n> found", d.Id())
+	}
+	memberIds := getInstanceIds(members)
+	if err := d.Set("name_prefix", d.Id()); err != nil {
+		return nil, err
+	}
+	// user data can't be read from API, default is stored so pool isn't replaced
+	if err := d.Set("user_data_gzip", false); err != nil {
+		return nil, err
+	}
+	if err := d.Set("instance_ids", memberIds); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+func loadInstancePoolData(ctx context.Context, d *schema.ResourceData, m interface{}, instances []odk.Instance) diag.Diagnostics {
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Id < instances[j].Id })
+	instanceIds := make([]int, len(instances))
+	ipAddresses := make([]string, len(instances))
+	privateIpAddresses := make([]string, len(instances))
+	for i, instance := range instances {
+		instanceIds[i] = int(instance.Id)
+		ipAddresses[i] = instance.IpAddress
+		privateIpAddresses[i] = instance.PrivateIpAddress
+	}
+
+	// Store everything
+	tflog.Debug(ctx, "Parsing returned data")
+	if d.Set("instance_ids", instanceIds) != nil {
+		return diag.Errorf("Can't retrieve pool members")
+	}
+	if d.Set("instances_count", len(instances)) != nil {
+		return diag.Errorf("Can't retrieve instances count")
+	}
+	if d.Set("ip_addresses", ipAddresses) != nil {
+		return diag.Errorf("Can't retrieve ip addresses")
+	}
+	if d.Set("private_ip_addresses", privateIpAddresses) != nil {
+		return diag.Errorf("Can't retrieve private ip addresses")
+	}
+	if len(instances) > 0 {
+		return loadInstancePoolSettings(ctx, d, m, instances[0])
+	}
+	return nil
+}
+
+// loadInstancePoolSettings reads shared settings of pool from its oldest member, so imported pool isn't replaced.
+func loadInstancePoolSettings(ctx context.Context, d *schema.ResourceData, m interface{}, instance odk.Instance) diag.Diagnostics {
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesGetDisks", map[string]interface{}{"id": instance.Id})
+	params := map[string]interface{}{
+		"pageSize": int32(math.MaxInt16),
+	}
+	disks, _, err := client.OCIApi.InstancesGetDisks(*auth, instance.Id, params)
+	if err != nil {
+		return diag.Errorf("Error while retrieving system disk for OCI %v: %s", instance.Id, err)
+	}
+	var systemDisk *odk.Disk
+	for i, disk := range disks.Items {
+		for _, connection := range disk.Connections {
+			if connection.Instance != nil && connection.Instance.Id == instance.Id && connection.IsSystemDisk {
+				systemDisk = &disks.Items[i]
+			}
+		}
+	}
+	if systemDisk == nil || systemDisk.Tier == nil {
+		return diag.Errorf("System disk for OCI %v not found", instance.Id)
+	}
+
+	_, opnIds, err := getOpnsData(client, *auth, instance.Id)
+	if err != nil {
+		return diag.Errorf("failed to load OPNs. %s", err)
+	}
+
+	ips, err := m.(*ClientConfig).ipCache.getInstanceIps(ctx, client, auth, instance.Id)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesGetSshKeys")
+	keys, _, err := client.OCIApi.InstancesGetSshKeys(*auth, instance.Id, params)
+	if err != nil {
+		return diag.Errorf("ODK Error in OCIApi.InstancesGetSshKeys. %s", err)
+	}
+	keyIds := make([]int32, 0)
+	for _, key := range keys.Items {
+		keyIds = append(keyIds, key.Id)
+	}
+	// authorization method isn't reported by API, members with ssh keys were created with them
+	authorizationMethod := DICT_LOGIN_TYPE_USER_AND_PASS
+	if len(keyIds) > 0 {
+		authorizationMethod = DICT_LOGIN_TYPE_SSH_KEYS
+	}
+
+	if d.Set("subregion_id", int(instance.Subregion.Id)) != nil {
+		return diag.Errorf("Can't retrieve subregion id")
+	}
+	if d.Set("template_id", instance.Template.Id) != nil {
+		return diag.Errorf("Can't retrieve template id")
+	}
+	if d.Set("type_id", instance.Type_.Id) != nil {
+		return diag.Errorf("Can't retrieve type id")
+	}
+	if d.Set("system_disk_class_id", systemDisk.Tier.Id) != nil {
+		return diag.Errorf("Can't retrieve system disk tier id")
+	}
+	if d.Set("system_disk_size", int(systemDisk.SpaceCapacity)) != nil {
+		return diag.Errorf("Can't retrieve system disk size")
+	}
+	if d.Set("opn_ids", opnIds) != nil {
+		return diag.Errorf("Can't retrieve opn connections")
+	}
+	if d.Set("without_public_ip", len(ips) == 0) != nil {
+		return diag.Errorf("Can't retrieve public ip option")
+	}
+	if d.Set("authorization_method_id", authorizationMethod) != nil {
+		return diag.Errorf("Can't retrieve authorization method")
+	}
+	if d.Set("ssh_keys_ids", keyIds) != nil {
+		return diag.Errorf("Can't retrieve ssh key ids")
+	}
+	return nil
+}
+
+// createInstancePoolMembers creates count instances with a single ticket and returns ids of all pool members.
+// Api does not report ids of instances created in bulk, so new members are found by listing <prefix>-<n> names.
+func createInstancePoolMembers(ctx context.Context, client odk.APIClient, auth *context.Context, d *schema.ResourceData, members []int32, count int32) ([]int32, error) {
+	if count <= 0 {
+		return members, nil
+	}
+	namePrefix := d.Get("name_prefix").(string)
+	authorizationMethod := int32(d.Get("authorization_method_id").(int))
+
+	initScript, err := getInstanceInitScript(d)
+	if err != nil {
+		return members, fmt.Errorf("can't prepare user data. %s", err)
+	}
+
+	createCommand := odk.CreateInstanceCommand{
+		InstanceName:          namePrefix,
+		SubregionId:           int32(d.Get("subregion_id").(int)),
+		DiskClass:             int32(d.Get("system_disk_class_id").(int)),
+		TemplateId:            int32(d.Get("template_id").(int)),
+		TypeId:                int32(d.Get("type_id").(int)),
+		AuthorizationMethodId: authorizationMethod,
+		OpnsIds:               castToInt32(d.Get("opn_ids").(*schema.Set).List()),
+		DiskSize:              int32(d.Get("system_disk_size").(int)),
+		InstancesCount:        count,
+		WithoutPublicIp:       d.Get("without_public_ip").(bool),
+		InitScript:            initScript,
+	}
+	if authorizationMethod == DICT_LOGIN_TYPE_SSH_KEYS {
+		sshKeyIds := castToInt32(d.Get("ssh_keys_ids").(*schema.Set).List())
+		if len(sshKeyIds) == 0 {
+			return members, fmt.Errorf("empty ssh keys list used with authorization method == ssh keys")
+		}
+		createCommand.SshKeysIds = sshKeyIds
+	}
+
+	existing, err := findInstancePoolMembers(ctx, client, auth, namePrefix)
+	if err != nil {
+		return members, err
+	}
+
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesPost", map[string]interface{}{"count": count, "name": namePrefix})
+	ticket, _, err := client.OCIApi.InstancesPost(*auth, createCommand)
+	if err != nil {
+		return members, fmt.Errorf("ODK Error in OCIApi.InstancesPost. %s", err)
+	}
+	createTicket, err := waitForTicket(client, auth, ticket)
+	if err != nil {
+		return members, fmt.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+	}
+
+	// collect whatever was created, even if ticket failed
+	current, err := findInstancePoolMembers(ctx, client, auth, namePrefix)
+	if err != nil {
+		return members, err
+	}
+	created := calcListAMinusListB(getInstanceIds(current), getInstanceIds(existing))
+	members = append(members, created...)
+	sort.Slice(members, func(i, j int) bool { return members[i] < members[j] })
+	tflog.Info(ctx, fmt.Sprintf("created pool members: %v", created))
+
+	if createTicket.Status.Id != DICT_TICKET_SUCCEED {
+		return members, fmt.Errorf("ticket status=%v", createTicket.Status.Id)
+	}
+	if int32(len(created)) != count {
+		return members, fmt.Errorf("expected %d new instances, found %d", count, len(created))
+	}
+	return members, nil
+}
+
+// findInstancePoolMembers lists instances named <prefix>-<n>, including ones not tracked in state.
+func findInstancePoolMembers(ctx context.Context, client odk.APIClient, auth *context.Context, namePrefix string) ([]odk.Instance, error) {
+	tflog.Debug(ctx, "calling ODK OCIApi.InstancesGet")
+	params := map[string]interface{}{
+		"query":    namePrefix,
+		"pageSize": int32(math.MaxInt16),
+	}
+	list, _, err := client.OCIApi.InstancesGet(*auth, params)
+	if err != nil {
+		return nil, fmt.Errorf("ODK Error in OCIApi.InstancesGet. %s", err)
+	}
+	instances := make([]odk.Instance, 0)
+	for _, instance := range list.Items {
+		if isInstancePoolMemberName(instance.Name, namePrefix) {
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+func getInstanceIds(instances []odk.Instance) []int32 {
+	ids := make([]int32, len(instances))
+	for i, instance := range instances {
+		ids[i] = instance.Id
+	}
+	return ids
+}
+
+// isInstancePoolMemberName accepts only names in <prefix>-<n> format (e.g. "web-1").
+func isInstancePoolMemberName(name string, namePrefix string) bool {
+	_, ok := instancePoolMemberIndex(name, namePrefix)
+	return ok
+}
+
+// instancePoolMemberIndex returns n of name in <prefix>-<n> format.
+func instancePoolMemberIndex(name string, namePrefix string) (int, bool) {
+	if !strings.HasPrefix(name, namePrefix+"-") {
+		return 0, false
+	}
+	suffix := strings.TrimPrefix(name, namePrefix+"-")
+	index, err := strconv.Atoi(suffix)
+	if err != nil || index < 1 || strconv.Itoa(index) != suffix {
+		return 0, false
+	}
+	return index, true
+}
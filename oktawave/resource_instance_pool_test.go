@@ -0,0 +1,172 @@
+package oktawave
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/oktawave-code/odk"
+)
+
+func TestAccOktawaveInstancePool_Basic(t *testing.T) {
+	poolConfig := func(count int) string {
+		return fmt.Sprintf(`
+resource "oktawave_opn" "test-opn" {
+	name = "test-pool-opn"
+}
+
+resource "oktawave_instance_pool" "test-pool" {
+	name_prefix = "test-pool"
+	instances_count = %d
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	without_public_ip = true
+	opn_ids = [oktawave_opn.test-opn.id]
+}
+`, count)
+	}
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: poolConfig(2),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_instance_pool.test-pool", "id", "test-pool"),
+					resource.TestCheckResourceAttr("oktawave_instance_pool.test-pool", "instances_count", "2"),
+					resource.TestCheckResourceAttr("oktawave_instance_pool.test-pool", "instance_ids.#", "2"),
+					resource.TestCheckResourceAttr("oktawave_instance_pool.test-pool", "private_ip_addresses.#", "2"),
+					resource.TestCheckResourceAttr("oktawave_instance_pool.test-pool", "ip_addresses.#", "2"),
+				),
+			},
+			{
+				Config: poolConfig(3),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_instance_pool.test-pool", "instances_count", "3"),
+					resource.TestCheckResourceAttr("oktawave_instance_pool.test-pool", "instance_ids.#", "3"),
+				),
+			},
+			{
+				Config: poolConfig(1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_instance_pool.test-pool", "instances_count", "1"),
+					resource.TestCheckResourceAttr("oktawave_instance_pool.test-pool", "instance_ids.#", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestIsInstancePoolMemberName(t *testing.T) {
+	cases := map[string]bool{
+		"web":      false,
+		"web-1":    true,
+		"web-12":   true,
+		"web1":     false,
+		"web_02":   false,
+		"web 3":    false,
+		"web-02":   false,
+		"web-0":    false,
+		"web-":     false,
+		"webapp":   false,
+		"web-db-1": false,
+		"db-web-1": false,
+	}
+	for name, expected := range cases {
+		if isInstancePoolMemberName(name, "web") != expected {
+			t.Errorf("isInstancePoolMemberName(%q, \"web\") should be %v", name, expected)
+		}
+	}
+}
+
+func TestResourceInstancePoolScaleOutUsesSingleTicket(t *testing.T) {
+	var commands []odk.CreateInstanceCommand
+	names := map[int32]string{10: "web-1", 12: "web-3", 20: "web1", 21: "web", 22: "db-web-7"}
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/instances":
+			var items []odk.Instance
+			for id, name := range names {
+				instance := testMockInstance(id)
+				instance.Name = name
+				items = append(items, instance)
+			}
+			testWriteJson(t, w, odk.ApiCollectionInstance{Items: items})
+		case r.Method == http.MethodPost && r.URL.Path == "/instances":
+			var createCommand odk.CreateInstanceCommand
+			if err := json.NewDecoder(r.Body).Decode(&createCommand); err != nil {
+				t.Errorf("can't decode instance create. %s", err)
+			}
+			commands = append(commands, createCommand)
+			names[30] = "web-4"
+			names[31] = "web-5"
+			testWriteJson(t, w, odk.Ticket{Id: 1, EndDate: time.Now(), ObjectId: 30, Status: &odk.DictionaryItem{Id: DICT_TICKET_SUCCEED}})
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/disks"):
+			var id int32
+			fmt.Sscanf(r.URL.Path, "/instances/%d/disks", &id)
+			testWriteJson(t, w, odk.ApiCollectionDisk{Items: []odk.Disk{{
+				Id:            100 + id,
+				SpaceCapacity: 5,
+				Tier:          &odk.DictionaryItem{Id: 48},
+				Connections:   []odk.DiskConnection{{Instance: &odk.BaseResource{Id: id}, IsSystemDisk: true}},
+			}}})
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/opns"):
+			testWriteJson(t, w, odk.ApiCollectionOpn{})
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/ssh_keys"):
+			testWriteJson(t, w, odk.ApiCollectionInstanceSshKey{})
+		case r.Method == http.MethodGet && r.URL.Path == "/floating_ips":
+			testWriteJson(t, w, odk.ApiCollectionIp{})
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/instances/"):
+			id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/instances/"))
+			testWriteJson(t, w, testMockInstance(int32(id)))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	state := &terraform.InstanceState{
+		ID: "web",
+		Attributes: map[string]string{
+			"name_prefix":     "web",
+			"instances_count": "1",
+			"instance_ids.#":  "1",
+			"instance_ids.0":  "10",
+		},
+	}
+	d, err := schema.InternalMap(resourceInstancePool().Schema).Data(state, &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"instances_count": {Old: "1", New: "3"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceInstancePoolUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	if len(commands) != 1 || commands[0].InstanceName != "web" || commands[0].InstancesCount != 2 {
+		t.Errorf("expected single ticket creating 2 instances, got %+v", commands)
+	}
+	if ids := d.Get("instance_ids").([]interface{}); !reflect.DeepEqual(ids, []interface{}{10, 30, 31}) {
+		t.Errorf("expected pool members [10 30 31], got %v", ids)
+	}
+	// shared settings are read back from members
+	if d.Get("template_id").(int) != 1021 || d.Get("system_disk_class_id").(int) != 48 || d.Get("system_disk_size").(int) != 5 || !d.Get("without_public_ip").(bool) || d.Get("authorization_method_id").(int) != DICT_LOGIN_TYPE_USER_AND_PASS {
+		t.Errorf("expected pool settings to be read from members, got template %v, disk class %v, disk size %v, without public ip %v, authorization method %v",
+			d.Get("template_id"), d.Get("system_disk_class_id"), d.Get("system_disk_size"), d.Get("without_public_ip"), d.Get("authorization_method_id"))
+	}
+}
@@ -0,0 +1,283 @@
+package oktawave
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/oktawave-code/odk"
+)
+
+func resourceInstanceSnapshot() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceInstanceSnapshotCreate,
+		ReadContext:   resourceInstanceSnapshotRead,
+		UpdateContext: resourceInstanceSnapshotUpdate,
+		DeleteContext: resourceInstanceSnapshotDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: map[string]*schema.Schema{
+			// Required
+			"instance_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Id of instance this snapshot is taken of.",
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Snapshot name.",
+			},
+			// Optional
+			"description": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Snapshot description.",
+			},
+			"restore_trigger": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Any change of this value rolls instance back to this snapshot, e.g. set it to a timestamp. Ignored on creation and when value is set for the first time, e.g. after import.",
+			},
+			// Computed
+			"creation_date": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Date when snapshot was taken.",
+			},
+			"last_change_date": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Date of last snapshot change.",
+			},
+			"creation_user_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Id of user who created snapshot.",
+			},
+			"is_current": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Tells if instance is currently based on this snapshot.",
+			},
+			"parent_snapshot_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Id of snapshot this snapshot was taken on top of.",
+			},
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(45 * time.Minute),
+		},
+		Description: "Point-in-time snapshot of instance. Snapshot size is not reported by Oktawave API.",
+	}
+}
+
+func resourceInstanceSnapshotCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "creating instance snapshot")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	instanceId := int32(d.Get("instance_id").(int))
+	name := d.Get("name").(string)
+
+	// ticket refers to instance, new snapshot is found by comparing snapshot lists
+	existingIds, err := findInstanceSnapshots(ctx, client, auth, instanceId, name)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	createCommand := odk.CreateUpdateSnapshotCommand{
+		Name:        name,
+		Description: d.Get("description").(string),
+	}
+	tflog.Debug(ctx, "calling ODK OCISnapshotsApi.InstancesPostSnapshot", map[string]interface{}{"instanceId": instanceId})
+	ticket, _, err := client.OCISnapshotsApi.InstancesPostSnapshot(*auth, instanceId, createCommand)
+	if err != nil {
+		return diag.Errorf("ODK Error in OCISnapshotsApi.InstancesPostSnapshot. %s", err)
+	}
+
+	createTicket, err := waitForTicket(client, auth, ticket)
+	if err != nil {
+		return diag.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+	}
+	if createTicket.Status.Id != DICT_TICKET_SUCCEED {
+		return diag.Errorf("Unable to create snapshot of instance %v. Ticket status=%v", instanceId, createTicket.Status.Id)
+	}
+
+	snapshotIds, err := findInstanceSnapshots(ctx, client, auth, instanceId, name)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	newIds := calcListAMinusListB(snapshotIds, existingIds)
+	if len(newIds) != 1 {
+		return diag.Errorf("Snapshot of instance %v was created, but it can't be identified. Found %v new snapshots named %q", instanceId, len(newIds), name)
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("successfully created snapshot. id=%v", newIds[0]))
+	d.SetId(strconv.Itoa(int(newIds[0])))
+
+	return resourceInstanceSnapshotRead(ctx, d, m)
+}
+
+func resourceInstanceSnapshotRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "reading instance snapshot")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	snapshotId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.Errorf("Invalid snapshot id: %v %s", d.Id(), err)
+	}
+
+	tflog.Debug(ctx, "calling ODK OCISnapshotsApi.SnapshotsGet_1", map[string]interface{}{"id": snapshotId})
+	snapshot, resp, err := client.OCISnapshotsApi.SnapshotsGet_1(*auth, int32(snapshotId), nil)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			tflog.Warn(ctx, fmt.Sprintf("Snapshot %v not found, removing it from state", snapshotId))
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("ODK Error in OCISnapshotsApi.SnapshotsGet_1. %s", err)
+	}
+
+	return loadInstanceSnapshotData(ctx, d, m, snapshot)
+}
+
+func resourceInstanceSnapshotUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "updating instance snapshot")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	snapshotId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.Errorf("Invalid snapshot id: %v %s", d.Id(), err)
+	}
+
+	if d.HasChanges("name", "description") {
+		updateCommand := odk.CreateUpdateSnapshotCommand{
+			Name:        d.Get("name").(string),
+			Description: d.Get("description").(string),
+		}
+		tflog.Debug(ctx, "calling ODK OCISnapshotsApi.SnapshotsPut", map[string]interface{}{"id": snapshotId})
+		_, _, err := client.OCISnapshotsApi.SnapshotsPut(*auth, int32(snapshotId), updateCommand)
+		if err != nil {
+			return diag.Errorf("ODK Error in OCISnapshotsApi.SnapshotsPut. %s", err)
+		}
+	}
+
+	// empty old value means trigger is being set for the first time, e.g. after creation or import
+	if oldTrigger, _ := d.GetChange("restore_trigger"); d.HasChange("restore_trigger") && oldTrigger.(string) != "" {
+		tflog.Info(ctx, fmt.Sprintf("restore trigger change detected, restoring instance %v to snapshot %v", d.Get("instance_id"), snapshotId))
+		tflog.Debug(ctx, "calling ODK OCISnapshotsApi.SnapshotsRestore", map[string]interface{}{"id": snapshotId})
+		ticket, _, err := client.OCISnapshotsApi.SnapshotsRestore(*auth, int32(snapshotId))
+		if err != nil {
+			return diag.Errorf("ODK Error in OCISnapshotsApi.SnapshotsRestore. %s", err)
+		}
+		ticket, err = waitForTicket(client, auth, ticket)
+		if err != nil {
+			return diag.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+		}
+		if ticket.Status.Id != DICT_TICKET_SUCCEED {
+			return diag.Errorf("Unable to restore snapshot %v. Ticket status=%v", snapshotId, ticket.Status.Id)
+		}
+	}
+
+	return resourceInstanceSnapshotRead(ctx, d, m)
+}
+
+func resourceInstanceSnapshotDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "deleting instance snapshot")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	snapshotId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.Errorf("Invalid snapshot id: %v %s", d.Id(), err)
+	}
+
+	tflog.Debug(ctx, "calling ODK OCISnapshotsApi.SnapshotsDelete", map[string]interface{}{"id": snapshotId})
+	ticket, _, err := client.OCISnapshotsApi.SnapshotsDelete(*auth, int32(snapshotId))
+	if err != nil {
+		return diag.Errorf("ODK Error in OCISnapshotsApi.SnapshotsDelete. %s", err)
+	}
+
+	ticket, err = waitForTicket(client, auth, ticket)
+	if err != nil {
+		return diag.Errorf("ODK Error in TicketsApi.TicketsGet. %s", err)
+	}
+	if ticket.Status.Id != DICT_TICKET_SUCCEED {
+		return diag.Errorf("Unable to delete snapshot. Ticket status=%v", ticket.Status.Id)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+func loadInstanceSnapshotData(ctx context.Context, d *schema.ResourceData, m interface{}, snapshot odk.Snapshot) diag.Diagnostics {
+	// Store everything
+	tflog.Debug(ctx, "Parsing returned data")
+	if snapshot.Instance != nil {
+		if d.Set("instance_id", snapshot.Instance.Id) != nil {
+			return diag.Errorf("Can't retrieve instance id")
+		}
+	}
+	if d.Set("name", snapshot.Name) != nil {
+		return diag.Errorf("Can't retrieve snapshot name")
+	}
+	if d.Set("description", snapshot.Description) != nil {
+		return diag.Errorf("Can't retrieve description")
+	}
+	if d.Set("creation_date", snapshot.CreationDate.String()) != nil {
+		return diag.Errorf("Can't retrieve creation date")
+	}
+	if d.Set("last_change_date", snapshot.LastChangeDate.String()) != nil {
+		return diag.Errorf("Can't retrieve last change date")
+	}
+	if snapshot.CreationUser != nil {
+		if d.Set("creation_user_id", snapshot.CreationUser.Id) != nil {
+			return diag.Errorf("Can't retrieve creation user id")
+		}
+	}
+	if d.Set("is_current", snapshot.IsCurrent) != nil {
+		return diag.Errorf("Can't retrieve current state")
+	}
+	if snapshot.SnapshotParent != nil {
+		if d.Set("parent_snapshot_id", snapshot.SnapshotParent.Id) != nil {
+			return diag.Errorf("Can't retrieve parent snapshot id")
+		}
+	}
+	return nil
+}
+
+// findInstanceSnapshots returns ids of instance snapshots with given name.
+func findInstanceSnapshots(ctx context.Context, client odk.APIClient, auth *context.Context, instanceId int32, name string) ([]int32, error) {
+	tflog.Debug(ctx, "calling ODK OCISnapshotsApi.SnapshotsGet", map[string]interface{}{"instanceId": instanceId})
+	params := map[string]interface{}{
+		"instanceId": instanceId,
+		"pageSize":   int32(math.MaxInt16),
+	}
+	list, _, err := client.OCISnapshotsApi.SnapshotsGet(*auth, params)
+	if err != nil {
+		return nil, fmt.Errorf("ODK Error in OCISnapshotsApi.SnapshotsGet. %s", err)
+	}
+	ids := make([]int32, 0)
+	for _, snapshot := range list.Items {
+		if snapshot.Name == name {
+			ids = append(ids, snapshot.Id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,154 @@
+package oktawave
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/oktawave-code/odk"
+)
+
+func testAccInstanceSnapshotConfig(snapshotName string, restoreTrigger string) string {
+	return fmt.Sprintf(`
+resource "oktawave_ip" "test-ip" {
+	subregion_id = 1
+}
+
+resource "oktawave_instance" "test-instance" {
+	name = "test-snapshot-instance"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	public_ips = [oktawave_ip.test-ip.id]
+}
+
+resource "oktawave_instance_snapshot" "test-snapshot" {
+	instance_id = oktawave_instance.test-instance.id
+	name = "%s"
+	description = "before risky change"
+	restore_trigger = "%s"
+}
+`, snapshotName, restoreTrigger)
+}
+
+func TestAccOktawaveInstanceSnapshot_Basic(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceSnapshotDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccInstanceSnapshotConfig("test-snapshot", ""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair("oktawave_instance_snapshot.test-snapshot", "instance_id", "oktawave_instance.test-instance", "id"),
+					resource.TestCheckResourceAttr("oktawave_instance_snapshot.test-snapshot", "name", "test-snapshot"),
+					resource.TestCheckResourceAttr("oktawave_instance_snapshot.test-snapshot", "description", "before risky change"),
+					resource.TestCheckResourceAttrSet("oktawave_instance_snapshot.test-snapshot", "creation_date"),
+					resource.TestCheckResourceAttrSet("oktawave_instance_snapshot.test-snapshot", "last_change_date"),
+					resource.TestCheckResourceAttrSet("oktawave_instance_snapshot.test-snapshot", "creation_user_id"),
+				),
+			},
+			{
+				Config: testAccInstanceSnapshotConfig("test-snapshot-renamed", "rollback-1"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_instance_snapshot.test-snapshot", "name", "test-snapshot-renamed"),
+					resource.TestCheckResourceAttr("oktawave_instance_snapshot.test-snapshot", "restore_trigger", "rollback-1"),
+				),
+			},
+			{
+				Config: testAccInstanceSnapshotConfig("test-snapshot-renamed", "rollback-2"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_instance_snapshot.test-snapshot", "restore_trigger", "rollback-2"),
+					resource.TestCheckResourceAttr("oktawave_instance_snapshot.test-snapshot", "is_current", "true"),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckInstanceSnapshotDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(*ClientConfig).odkClient
+	auth := testAccProvider.Meta().(*ClientConfig).odkAuth
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "oktawave_instance_snapshot" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(rs.Primary.ID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Failed to parse resource id %s.", rs.Primary.ID)
+		}
+
+		_, resp, err := client.OCISnapshotsApi.SnapshotsGet_1(*auth, int32(id), nil)
+		if err == nil {
+			return fmt.Errorf("Snapshot with id %d not destroyed correctly.", id)
+		}
+		if resp == nil || (resp.StatusCode != 404 && resp.StatusCode != 403) {
+			return fmt.Errorf("Failed to call for snapshot. Caused by: %s.", err)
+		}
+	}
+	return testAccCheckInstanceDatasourceDestroy(s)
+}
+
+func TestResourceInstanceSnapshotRestoreTrigger(t *testing.T) {
+	cases := map[string]struct {
+		old      string
+		new      string
+		expected []string
+	}{
+		"first value after import": {
+			old:      "",
+			new:      "rollback-1",
+			expected: []string{"GET /snapshots/3"},
+		},
+		"changed value": {
+			old:      "rollback-1",
+			new:      "rollback-2",
+			expected: []string{"POST /snapshots/3/restore_ticket", "GET /snapshots/3"},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var requests []string
+			config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/snapshots/3":
+					testWriteJson(t, w, odk.Snapshot{Id: 3, Name: "test-snapshot", Instance: &odk.BaseResource{Id: 5}})
+				case r.Method == http.MethodPost && r.URL.Path == "/snapshots/3/restore_ticket":
+					testWriteJson(t, w, odk.Ticket{Id: 1, EndDate: time.Now(), Status: &odk.DictionaryItem{Id: DICT_TICKET_SUCCEED}})
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+
+			state := &terraform.InstanceState{
+				ID:         "3",
+				Attributes: map[string]string{"instance_id": "5", "name": "test-snapshot", "restore_trigger": c.old},
+			}
+			d, err := schema.InternalMap(resourceInstanceSnapshot().Schema).Data(state, &terraform.InstanceDiff{
+				Attributes: map[string]*terraform.ResourceAttrDiff{
+					"restore_trigger": {Old: c.old, New: c.new},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diags := resourceInstanceSnapshotUpdate(context.Background(), d, config); diags.HasError() {
+				t.Fatalf("unexpected error %v", diags)
+			}
+			if fmt.Sprint(requests) != fmt.Sprint(c.expected) {
+				t.Errorf("expected requests %v, got %v", c.expected, requests)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package oktawave
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"testing"
 
@@ -762,39 +763,21 @@ resource "oktawave_template" "template" {
 }
 `
 
-	instanceUpdateConfig := `
-resource "oktawave_instance" "test-instance" {
-	name = "test-instance"
-	subregion_id = 1
-	system_disk_class_id = 48
-	template_id = 1021
-	type_id = 1049
-}
-
+	// instance_id is write only and refers to instance which no longer exists
+	instanceRemovedConfig := `
 resource "oktawave_template" "template" {
-	depends_on = [oktawave_instance.test-instance]
-	instance_id = oktawave_instance.test-instance.id
+	instance_id = 0
 	name = "test-template"
 	description = "test-template"
 	version = "0.1"
 	system_category_id = 1277
 	default_type_id = 1047
 	minimum_type_id = 1047
-}
-`
 
-	instanceRemovedConfig := func(instanceId int32) string {
-		return fmt.Sprintf(`
-resource "oktawave_template" "template" {
-	instance_id = %d
-	name = "test-template"
-	description = "test-template"
-	version = "0.1"
-	system_category_id = 1277
-	default_type_id = 1047
-	minimum_type_id = 1047
-}`, instanceId)
+	lifecycle {
+		ignore_changes = [instance_id]
 	}
+}`
 	resource.ParallelTest(t, resource.TestCase{
 		PreCheck:     func() { testAccPreCheck(t) },
 		Providers:    testAccProviders,
@@ -807,18 +790,181 @@ resource "oktawave_template" "template" {
 				),
 			},
 			{
-				Config: templateConfig,
+				// converted instance is dropped from state on refresh, so definition left in place plans new instance
+				Config:             templateConfig,
+				ExpectNonEmptyPlan: true,
 			},
 			{
-				Config: instanceUpdateConfig,
+				// instance definition can be removed without issuing delete
+				Config: instanceRemovedConfig,
 				Check: resource.ComposeAggregateTestCheckFunc(
-					testAccCheckInstanceDoesntExist("oktawave_instance.test-instance"),
-					resource.TestCheckResourceAttrPair("oktawave_instance.test-instance", "converted_to_template_id", "oktawave_template.template", "id"),
+					testAccCheckInstanceConvertedAndRemoved("oktawave_instance.test-instance", &instance),
 				),
 			},
+		},
+	})
+}
+
+func TestAccOktawaveInstance_UserData(t *testing.T) {
+	var instance odk.Instance
+	instanceConfig := `
+resource "oktawave_ip" "test-ip1" {
+	subregion_id = 1
+}
+
+resource "oktawave_ip" "test-ip2" {
+	subregion_id = 1
+}
+
+data "oktawave_cloudinit_config" "config" {
+	gzip = true
+	part {
+		content_type = "text/x-shellscript"
+		content = "echo \"Hello world\""
+	}
+}
+
+resource "oktawave_instance" "test-instance1" {
+	name = "test-instance1"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	user_data = "echo \"Hello world\""
+	user_data_gzip = true
+	public_ips = [oktawave_ip.test-ip1.id]
+}
+
+resource "oktawave_instance" "test-instance2" {
+	name = "test-instance2"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	user_data_base64 = data.oktawave_cloudinit_config.config.rendered
+	public_ips = [oktawave_ip.test-ip2.id]
+}
+`
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: instanceConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckInstanceExists("oktawave_instance.test-instance1", &instance),
+					resource.TestCheckResourceAttr("oktawave_instance.test-instance1", "user_data", "de68090eec75cdf0070ebd5fd4afa555b0d5712c"),
+					resource.TestCheckResourceAttr("oktawave_instance.test-instance1", "init_script", ""),
+					resource.TestCheckResourceAttrSet("oktawave_instance.test-instance2", "user_data_base64"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccOktawaveInstance_WaitForReadiness(t *testing.T) {
+	var instance odk.Instance
+	instanceConfig := `
+resource "oktawave_ip" "test-ip1" {
+	subregion_id = 1
+}
+
+resource "oktawave_instance" "test-instance1" {
+	name = "test-instance1"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	public_ips = [oktawave_ip.test-ip1.id]
+	wait_for_status = 86
+	wait_for_tcp_port = 22
+}
+`
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: instanceConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckInstanceExists("oktawave_instance.test-instance1", &instance),
+					resource.TestCheckResourceAttr("oktawave_instance.test-instance1", "status_id", "86"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccOktawaveInstance_PlanValidation(t *testing.T) {
+	instanceConfig := func(attributes string) string {
+		return fmt.Sprintf(`
+resource "oktawave_opn" "test-opn" {
+	name = "test-opn"
+}
+
+resource "oktawave_instance" "test-instance" {
+	name = "test-instance"
+	system_disk_class_id = 48
+	template_id = 1021
+	opn_ids = [oktawave_opn.test-opn.id]
+	%s
+}
+`, attributes)
+	}
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      instanceConfig("subregion_id = 1\n\ttype_id = 1047\n\tsystem_disk_size = 4"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("system_disk_size must be at least 5 GB"),
+			},
+			{
+				Config:      instanceConfig("subregion_id = 1\n\ttype_id = 1047\n\tauthorization_method_id = 1398"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("ssh_keys_ids can't be empty"),
+			},
+			{
+				Config:      instanceConfig("subregion_id = 999999\n\ttype_id = 1047"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("subregion 999999 not found"),
+			},
 			{
-				// check if instance definition can be removed safely
-				Config: instanceRemovedConfig(instance.Id),
+				Config: instanceConfig("subregion_id = 1\n\ttype_id = 1047\n\tsystem_disk_size = 10"),
+			},
+			{
+				Config:      instanceConfig("subregion_id = 1\n\ttype_id = 1047\n\tsystem_disk_size = 6"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("can't shrink it from 10 GB to 6 GB"),
+			},
+		},
+	})
+}
+
+func TestAccOktawaveInstance_PlanValidationWithoutPublicIp(t *testing.T) {
+	instanceConfig := `
+resource "oktawave_instance" "test-instance" {
+	name = "test-instance"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	without_public_ip = true
+}
+`
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      instanceConfig,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("must have at least one entry in opn_ids"),
 			},
 		},
 	})
@@ -857,34 +1003,87 @@ func testAccCheckInstanceExists(resourceName string, instance *odk.Instance) res
 	}
 }
 
-func testAccCheckInstanceDoesntExist(resourceName string) resource.TestCheckFunc {
-	return func(s *terraform.State) error {
-		rs, ok := s.RootModule().Resources[resourceName]
-		if !ok {
-			return fmt.Errorf("Not found: %s", resourceName)
-		}
+func TestAccOktawaveInstance_DataDisks(t *testing.T) {
+	var instance odk.Instance
+	instanceConfig := `
+resource "oktawave_ip" "test-ip1" {
+	subregion_id = 1
+}
 
-		if rs.Primary.ID == "" {
-			return fmt.Errorf("Instance ID is not set")
+resource "oktawave_instance" "test-instance1" {
+	name = "test-instance1"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	public_ips = [oktawave_ip.test-ip1.id]
+	%s
+}
+`
+	twoDisks := `
+	data_disk {
+		name = "test-data-disk1"
+		tier_id = 48
+	}
+	data_disk {
+		name = "test-data-disk2"
+		tier_id = 48
+		capacity = 10
+	}`
+	oneResizedDisk := `
+	data_disk {
+		name = "test-data-disk1-renamed"
+		tier_id = 48
+		capacity = 15
+	}`
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(instanceConfig, twoDisks),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckInstanceExists("oktawave_instance.test-instance1", &instance),
+					resource.TestCheckResourceAttr("oktawave_instance.test-instance1", "data_disk.#", "2"),
+					resource.TestCheckResourceAttr("oktawave_instance.test-instance1", "data_disk.0.capacity", "5"),
+					resource.TestCheckResourceAttr("oktawave_instance.test-instance1", "data_disk.1.capacity", "10"),
+					resource.TestCheckResourceAttrSet("oktawave_instance.test-instance1", "data_disk.0.id"),
+					resource.TestCheckResourceAttrSet("oktawave_instance.test-instance1", "data_disk.1.id"),
+					resource.TestCheckResourceAttr("oktawave_instance.test-instance1", "disks_ids.#", "0"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(instanceConfig, oneResizedDisk),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckInstanceExists("oktawave_instance.test-instance1", &instance),
+					resource.TestCheckResourceAttr("oktawave_instance.test-instance1", "data_disk.#", "1"),
+					resource.TestCheckResourceAttr("oktawave_instance.test-instance1", "data_disk.0.name", "test-data-disk1-renamed"),
+					resource.TestCheckResourceAttr("oktawave_instance.test-instance1", "data_disk.0.capacity", "15"),
+					resource.TestCheckResourceAttr("oktawave_instance.test-instance1", "disks_ids.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckInstanceConvertedAndRemoved(resourceName string, instance *odk.Instance) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if _, ok := s.RootModule().Resources[resourceName]; ok {
+			return fmt.Errorf("Instance %s is still in state", resourceName)
 		}
 
 		client := testAccProvider.Meta().(*ClientConfig).odkClient
 		auth := testAccProvider.Meta().(*ClientConfig).odkAuth
 
-		id, err := strconv.ParseInt(rs.Primary.ID, 10, 64)
+		template, _, err := client.OCIApi.InstancesGetTemplateByBaseVirtualMachineId(*auth, instance.Id, nil)
 		if err != nil {
-			return fmt.Errorf("Failed to parse resource id %s.", rs.Primary.ID)
+			return fmt.Errorf("Template of instance %d not found. Caused by: %s.", instance.Id, err)
 		}
-
-		_, resp, err := client.OCIApi.InstancesGet_2(*auth, int32(id), nil)
-		if err != nil {
-			if resp.StatusCode == 404 {
-				return nil
-			}
-
-			return fmt.Errorf("Failed to call for instance. Caused by: %s.", err)
+		if template.Id == 0 {
+			return fmt.Errorf("Instance %d was not converted to template", instance.Id)
 		}
-
-		return fmt.Errorf("Instance exists.")
+		return nil
 	}
 }
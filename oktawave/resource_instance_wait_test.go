@@ -0,0 +1,97 @@
+package oktawave
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/oktawave-code/odk"
+)
+
+func TestWaitForInstanceStatus(t *testing.T) {
+	instanceReadinessPollInterval = 10 * time.Millisecond
+	defer func() { instanceReadinessPollInterval = 10 * time.Second }()
+
+	calls := 0
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/instances/1" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		calls++
+		instance := odk.Instance{
+			Id:                1,
+			Status:            &odk.DictionaryItem{Id: DICT_INSTANCE_STATUS_INITIALIZING},
+			VmWareToolsStatus: &odk.DictionaryItem{Id: 1},
+		}
+		if calls >= 3 {
+			instance.Status.Id = DICT_INSTANCE_STATUS_ON
+		}
+		if calls >= 4 {
+			instance.VmWareToolsStatus.Id = 2
+		}
+		testWriteJson(t, w, instance)
+	}))
+
+	instance, err := waitForInstanceStatus(context.Background(), config.odkClient, config.odkAuth, 1, DICT_INSTANCE_STATUS_ON, 2, time.Now().Add(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 || instance.Status.Id != DICT_INSTANCE_STATUS_ON {
+		t.Errorf("expected 4 calls ending with running instance, got %d calls and status %v", calls, instance.Status.Id)
+	}
+
+	calls = 0
+	_, err = waitForInstanceStatus(context.Background(), config.odkClient, config.odkAuth, 1, DICT_INSTANCE_STATUS_OFF, 0, time.Now().Add(50*time.Millisecond))
+	if err == nil {
+		t.Error("expected timeout error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	_, err = waitForInstanceStatus(ctx, config.odkClient, config.odkAuth, 1, DICT_INSTANCE_STATUS_OFF, 0, time.Now().Add(5*time.Second))
+	if err == nil || calls != 1 {
+		t.Errorf("expected cancelled wait to stop after first check, got %d calls and error %v", calls, err)
+	}
+}
+
+func TestWaitForTcpPort(t *testing.T) {
+	instanceReadinessPollInterval = 10 * time.Millisecond
+	defer func() { instanceReadinessPollInterval = 10 * time.Second }()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	if err := waitForTcpPort(context.Background(), "127.0.0.1", port, time.Now().Add(time.Second)); err != nil {
+		t.Errorf("listening port not detected. %s", err)
+	}
+
+	listener.Close()
+	err = waitForTcpPort(context.Background(), "127.0.0.1", port, time.Now().Add(50*time.Millisecond))
+	if err == nil {
+		t.Errorf("closed port %s reported as open", strconv.Itoa(port))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	started := time.Now()
+	err = waitForTcpPort(ctx, "127.0.0.1", port, time.Now().Add(5*time.Second))
+	if err == nil || time.Since(started) > time.Second {
+		t.Errorf("expected cancelled wait to stop immediately, got error %v after %v", err, time.Since(started))
+	}
+}
@@ -3,13 +3,17 @@ package oktawave
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/oktawave-code/odk"
 )
 
@@ -20,9 +24,10 @@ func resourceIpAddress() *schema.Resource {
 		UpdateContext: resourceIpAddressUpdate,
 		DeleteContext: resourceIpAddressDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importStatePassthroughWithDeletionProtection,
 		},
 		Schema: map[string]*schema.Schema{
+			"deletion_protection": deletionProtectionSchema(),
 			"subregion_id": {
 				Type:        schema.TypeInt,
 				Required:    true,
@@ -34,16 +39,23 @@ func resourceIpAddress() *schema.Resource {
 				Description: "Comment to this ip. Helps to quickly identify IP purpose.",
 			},
 			"rev_dns": {
-				Type:        schema.TypeString,
-				Optional:    true,
-				Computed:    true,
-				Description: "Reverse DNS for v4 IP.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validateHostname,
+				Description:  "Reverse DNS for v4 IP. Restored to default when IP is destroyed.",
 			},
 			"rev_dns_v6": {
-				Type:        schema.TypeString,
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validateHostname,
+				Description:  "Reverse DNS for v6 IP. Restored to default when IP is destroyed.",
+			},
+			"verify_rev_dns": {
+				Type:        schema.TypeBool,
 				Optional:    true,
-				Computed:    true,
-				Description: "Reverse DNS for v6 IP.",
+				Description: "When true, reverse DNS is set only if hostname resolves to IP address. Provider dns_resolver is used for lookups.",
 			},
 			"type_id": {
 				Type:        schema.TypeInt,
@@ -58,7 +70,7 @@ func resourceIpAddress() *schema.Resource {
 			"address_v6": {
 				Type:        schema.TypeString,
 				Computed:    true,
-				Description: "IPv6 address",
+				Description: "IPv6 address. Oktawave API doesn't allow to request IPv6 address when booking IP.",
 			},
 			"gateway": {
 				Type:        schema.TypeString,
@@ -96,9 +108,12 @@ func resourceIpAddress() *schema.Resource {
 				Description: "DHCP branch",
 			},
 			"mode_id": {
-				Type:        schema.TypeInt,
-				Computed:    true,
-				Description: "Value from dictionary #301 (Normal/Floating/KAS)",
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Computed:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IntInSlice([]int{DICT_IP_MODE_NORMAL, DICT_IP_MODE_FLOATING}),
+				Description:  "Value from dictionary #301. Normal IPs and floating IPs (e.g. for keepalived failover) can be booked. Oktawave API can't switch mode of existing IP, so changing it replaces IP. KAS IPs can't be booked.",
 			},
 			"creation_user_id": {
 				Type:        schema.TypeInt,
@@ -124,12 +139,25 @@ func resourceIpAddressCreate(ctx context.Context, d *schema.ResourceData, m inte
 		SubregionId: int32(subregion_id),
 	}
 
-	tflog.Debug(ctx, "calling ODK FloatingIPsApi.FloatingIpsBookNewIp")
-	ip, _, err := client.OCIInterfacesApi.InstancesBookNewIp(*auth, bookCommand)
-	if err != nil {
-		return diag.Errorf("ODK Error in FloatingIPsApi.FloatingIpsBookNewIp. %s", err)
+	var ip odk.Ip
+	var err error
+	if d.Get("mode_id").(int) == DICT_IP_MODE_FLOATING {
+		tflog.Debug(ctx, "calling ODK FloatingIPsApi.FloatingIpsBookNewIp")
+		ip, _, err = client.FloatingIPsApi.FloatingIpsBookNewIp(*auth, bookCommand)
+		if err != nil {
+			return diag.Errorf("ODK Error in FloatingIPsApi.FloatingIpsBookNewIp. %s", err)
+		}
+	} else {
+		tflog.Debug(ctx, "calling ODK OCIInterfacesApi.InstancesBookNewIp")
+		ip, _, err = client.OCIInterfacesApi.InstancesBookNewIp(*auth, bookCommand)
+		if err != nil {
+			return diag.Errorf("ODK Error in OCIInterfacesApi.InstancesBookNewIp. %s", err)
+		}
 	}
 
+	tflog.Info(ctx, fmt.Sprintf("successfully created IP address. id=%v", ip.Address))
+	d.SetId(strconv.Itoa(int(ip.Id)))
+
 	updateCommand := odk.UpdateIpCommand{}
 	updateNeeded := false
 	comment, isSet := d.GetOk("comment")
@@ -147,6 +175,14 @@ func resourceIpAddressCreate(ctx context.Context, d *schema.ResourceData, m inte
 		updateCommand.RevDnsV6 = revDnsV6.(string)
 		updateNeeded = true
 	}
+	if d.Get("verify_rev_dns").(bool) {
+		if err := verifyRevDns(ctx, m, updateCommand.RevDns, ip.Address); err != nil {
+			return diag.FromErr(err)
+		}
+		if err := verifyRevDns(ctx, m, updateCommand.RevDnsV6, ip.AddressV6); err != nil {
+			return diag.FromErr(err)
+		}
+	}
 	if updateNeeded {
 		tflog.Debug(ctx, "calling ODK FloatingIPsApi.FloatingIpsUpdateIp")
 		_, _, err := client.OCIInterfacesApi.InstancesUpdateIp(*auth, ip.Id, updateCommand)
@@ -155,9 +191,6 @@ func resourceIpAddressCreate(ctx context.Context, d *schema.ResourceData, m inte
 		}
 	}
 
-	tflog.Info(ctx, fmt.Sprintf("successfully created IP address. id=%v", ip.Address))
-	d.SetId(strconv.Itoa(int(ip.Id)))
-
 	return resourceIpAddressRead(ctx, d, m)
 }
 
@@ -209,6 +242,14 @@ func resourceIpAddressUpdate(ctx context.Context, d *schema.ResourceData, m inte
 		updateCommand.RevDnsV6 = d.Get("rev_dns_v6").(string)
 		updateNeeded = true
 	}
+	if d.Get("verify_rev_dns").(bool) {
+		if err := verifyRevDns(ctx, m, updateCommand.RevDns, d.Get("address").(string)); err != nil {
+			return diag.FromErr(err)
+		}
+		if err := verifyRevDns(ctx, m, updateCommand.RevDnsV6, d.Get("address_v6").(string)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
 	if updateNeeded {
 		tflog.Debug(ctx, "calling ODK FloatingIPsApi.FloatingIpsUpdateIp")
 		_, _, err := client.OCIInterfacesApi.InstancesUpdateIp(*auth, (int32)(id), updateCommand)
@@ -241,6 +282,10 @@ func resourceIpAddressUpdate(ctx context.Context, d *schema.ResourceData, m inte
 func resourceIpAddressDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	tflog.Info(ctx, "deleting ip address")
 
+	if diags := checkDeletionProtection(d); diags != nil {
+		return diags
+	}
+
 	client := m.(*ClientConfig).odkClient
 	auth := m.(*ClientConfig).odkAuth
 
@@ -259,25 +304,44 @@ func resourceIpAddressDelete(ctx context.Context, d *schema.ResourceData, m inte
 	}
 
 	if ip.Instance != nil {
-		ticket, _, err := detachIpById(client, auth, ip.Instance.Id, (int32)(id))
+		err := detachIpFromInstance(client, auth, ip, ip.Instance.Id)
+		m.(*ClientConfig).ipCache.invalidate()
 		if err != nil {
-			return diag.Errorf("Can't detach IP. %s", err)
-		}
-		if ticket.Status.Id != DICT_TICKET_SUCCEED {
-			return diag.Errorf("Can't detach IP. Ticket status=%v", ticket.Status.Id)
+			return diag.FromErr(err)
 		}
 	}
 
-	tflog.Debug(ctx, "calling ODK FloatingIPsApi.FloatingIpsDeleteIp")
-	_, _, err = client.OCIInterfacesApi.InstancesDeleteIp(*auth, (int32)(id))
-	if err != nil && err.Error() != "EOF" { // "EOF" condition is a patch for ODK 1.4 bug: it reports error when API returns empty body
-		return diag.Errorf("ODK Error in FloatingIPsApi.FloatingIpsDeleteIp. %s", err)
+	if err := releaseIp(ctx, client, auth, ip); err != nil {
+		return diag.FromErr(err)
 	}
 
 	d.SetId("")
 	return nil
 }
 
+// releaseIp restores default reverse DNS of unattached IP and gives it back to Oktawave.
+func releaseIp(ctx context.Context, client odk.APIClient, auth *context.Context, ip odk.Ip) error {
+	// next holder of this address shouldn't inherit our PTR records
+	restoreCommand := odk.UpdateIpCommand{
+		RestoreRevDns:   ip.RevDns != "",
+		RestoreRevDnsV6: ip.AddressV6 != "" && ip.RevDnsV6 != "",
+	}
+	if restoreCommand.RestoreRevDns || restoreCommand.RestoreRevDnsV6 {
+		tflog.Debug(ctx, "calling ODK FloatingIPsApi.FloatingIpsUpdateIp", map[string]interface{}{"id": ip.Id})
+		_, _, err := client.OCIInterfacesApi.InstancesUpdateIp(*auth, ip.Id, restoreCommand)
+		if err != nil && err.Error() != "EOF" { // "EOF" condition is a patch for ODK 1.4 bug: it reports error when API returns empty body
+			return fmt.Errorf("ODK Error in FloatingIPsApi.FloatingIpsUpdateIp. %s", err)
+		}
+	}
+
+	tflog.Debug(ctx, "calling ODK FloatingIPsApi.FloatingIpsDeleteIp", map[string]interface{}{"id": ip.Id})
+	_, _, err := client.OCIInterfacesApi.InstancesDeleteIp(*auth, ip.Id)
+	if err != nil && err.Error() != "EOF" { // "EOF" condition is a patch for ODK 1.4 bug: it reports error when API returns empty body
+		return fmt.Errorf("ODK Error in FloatingIPsApi.FloatingIpsDeleteIp. %s", err)
+	}
+	return nil
+}
+
 func loadIpAddressData(ctx context.Context, d *schema.ResourceData, m interface{}, ip odk.Ip) diag.Diagnostics {
 	// Store everything
 	tflog.Debug(ctx, "Parsing returned data")
@@ -344,3 +408,42 @@ func loadIpAddressData(ctx context.Context, d *schema.ResourceData, m interface{
 	}
 	return nil
 }
+
+var hostnameRegexp = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.?$`)
+
+func validateHostname(i interface{}, k string) ([]string, []error) {
+	hostname, ok := i.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+	if hostname == "" {
+		return nil, nil
+	}
+	if len(strings.TrimSuffix(hostname, ".")) > 253 || !hostnameRegexp.MatchString(hostname) {
+		return nil, []error{fmt.Errorf("%s must be a valid hostname, got %q", k, hostname)}
+	}
+	return nil, nil
+}
+
+// verifyRevDns checks that hostname resolves to address, so reverse and forward DNS are consistent.
+func verifyRevDns(ctx context.Context, m interface{}, hostname string, address string) error {
+	if hostname == "" || address == "" {
+		return nil
+	}
+	resolver := m.(*ClientConfig).resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	tflog.Debug(ctx, "resolving reverse DNS hostname", map[string]interface{}{"hostname": hostname})
+	addresses, err := resolver.LookupHost(ctx, hostname)
+	if err != nil {
+		return fmt.Errorf("can't verify reverse DNS %q of IP %s. %s", hostname, address, err)
+	}
+	expected := net.ParseIP(address)
+	for _, resolved := range addresses {
+		if expected.Equal(net.ParseIP(resolved)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("reverse DNS %q of IP %s resolves to %v, not to IP address", hostname, address, addresses)
+}
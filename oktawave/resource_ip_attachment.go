@@ -0,0 +1,213 @@
+package oktawave
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/oktawave-code/odk"
+)
+
+func resourceIpAttachment() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceIpAttachmentCreate,
+		ReadContext:   resourceIpAttachmentRead,
+		UpdateContext: resourceIpAttachmentUpdate,
+		DeleteContext: resourceIpAttachmentDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: map[string]*schema.Schema{
+			// Required
+			"ip_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Id of attached IP.",
+			},
+			"instance_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "Id of instance IP is attached to. Changing it moves IP to another instance.",
+			},
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(45 * time.Minute),
+			Update: schema.DefaultTimeout(45 * time.Minute),
+		},
+		Description: "Attachment of public IP to instance. Id is the same as IP id. Don't manage the same IP with public_ips of oktawave_instance.",
+	}
+}
+
+func resourceIpAttachmentCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "creating ip attachment")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	ipId := int32(d.Get("ip_id").(int))
+	instanceId := int32(d.Get("instance_id").(int))
+
+	ip, _, err := getIpAttachmentIp(ctx, client, auth, ipId)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if ip.Instance != nil && ip.Instance.Id != instanceId {
+		return diag.Errorf("IP %v is already attached to instance %v. Detach it first, or import it as oktawave_ip_attachment", ipId, ip.Instance.Id)
+	}
+	if ip.Instance == nil {
+		if err := attachIpToInstance(client, auth, ip, instanceId); err != nil {
+			return diag.FromErr(err)
+		}
+		m.(*ClientConfig).ipCache.invalidate()
+	}
+
+	d.SetId(strconv.Itoa(int(ipId)))
+	return resourceIpAttachmentRead(ctx, d, m)
+}
+
+func resourceIpAttachmentRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "reading ip attachment")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	ipId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.Errorf("Invalid Ip id: %v %s", d.Id(), err)
+	}
+
+	tflog.Debug(ctx, "calling ODK OCIInterfacesApi.InstancesGetInstanceIp", map[string]interface{}{"id": ipId})
+	ip, resp, err := client.OCIInterfacesApi.InstancesGetInstanceIp(*auth, int32(ipId), nil)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			tflog.Warn(ctx, fmt.Sprintf("IP %v not found, removing attachment from state", ipId))
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("ODK Error in OCIInterfacesApi.InstancesGetInstanceIp. %s", err)
+	}
+
+	if ip.Instance == nil {
+		tflog.Warn(ctx, fmt.Sprintf("IP %v is no longer attached to any instance, removing attachment from state", ipId))
+		d.SetId("")
+		return nil
+	}
+
+	// Store everything
+	tflog.Debug(ctx, "Parsing returned data")
+	if d.Set("ip_id", ip.Id) != nil {
+		return diag.Errorf("Can't retrieve IP id")
+	}
+	if d.Set("instance_id", ip.Instance.Id) != nil {
+		return diag.Errorf("Can't retrieve instance id")
+	}
+	return nil
+}
+
+func resourceIpAttachmentUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "updating ip attachment")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	ipId := int32(d.Get("ip_id").(int))
+	if d.HasChange("instance_id") {
+		oldInstanceId, newInstanceId := d.GetChange("instance_id")
+		tflog.Info(ctx, fmt.Sprintf("moving IP %v from instance %v to instance %v", ipId, oldInstanceId, newInstanceId))
+		ip, _, err := getIpAttachmentIp(ctx, client, auth, ipId)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		// IP is unreachable between both tickets, nothing else is done in between
+		err = detachIpFromInstance(client, auth, ip, int32(oldInstanceId.(int)))
+		if err == nil {
+			err = attachIpToInstance(client, auth, ip, int32(newInstanceId.(int)))
+		}
+		m.(*ClientConfig).ipCache.invalidate()
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return resourceIpAttachmentRead(ctx, d, m)
+}
+
+func resourceIpAttachmentDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "deleting ip attachment")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	ipId := int32(d.Get("ip_id").(int))
+	// state may be outdated, IP is detached only from instance holding it now
+	ip, resp, err := getIpAttachmentIp(ctx, client, auth, ipId)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			tflog.Warn(ctx, fmt.Sprintf("IP %v no longer exists", ipId))
+			d.SetId("")
+			return nil
+		}
+		return diag.FromErr(err)
+	}
+	if ip.Instance != nil {
+		err := detachIpFromInstance(client, auth, ip, ip.Instance.Id)
+		m.(*ClientConfig).ipCache.invalidate()
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	d.SetId("")
+	return nil
+}
+
+func getIpAttachmentIp(ctx context.Context, client odk.APIClient, auth *context.Context, ipId int32) (odk.Ip, *http.Response, error) {
+	tflog.Debug(ctx, "calling ODK OCIInterfacesApi.InstancesGetInstanceIp", map[string]interface{}{"id": ipId})
+	ip, resp, err := client.OCIInterfacesApi.InstancesGetInstanceIp(*auth, ipId, nil)
+	if err != nil {
+		return ip, resp, fmt.Errorf("ODK Error in OCIInterfacesApi.InstancesGetInstanceIp. %s", err)
+	}
+	return ip, resp, nil
+}
+
+// attachIpToInstance attaches IP with ticket matching its mode. Floating IPs are identified by address.
+func attachIpToInstance(client odk.APIClient, auth *context.Context, ip odk.Ip, instanceId int32) error {
+	var ticket odk.Ticket
+	var err error
+	if ip.Mode != nil && ip.Mode.Id == DICT_IP_MODE_FLOATING {
+		ticket, _, err = attachIp(client, auth, instanceId, ip.Address)
+	} else {
+		ticket, _, err = attachIpById(client, auth, instanceId, ip.Id)
+	}
+	if err != nil {
+		return fmt.Errorf("can't attach IP %v to instance %v. %s", ip.Id, instanceId, err)
+	}
+	if ticket.Status.Id != DICT_TICKET_SUCCEED {
+		return fmt.Errorf("can't attach IP %v to instance %v. Ticket status=%v", ip.Id, instanceId, ticket.Status.Id)
+	}
+	return nil
+}
+
+// detachIpFromInstance detaches IP with ticket matching its mode. Floating IPs are identified by address.
+func detachIpFromInstance(client odk.APIClient, auth *context.Context, ip odk.Ip, instanceId int32) error {
+	var ticket odk.Ticket
+	var err error
+	if ip.Mode != nil && ip.Mode.Id == DICT_IP_MODE_FLOATING {
+		ticket, _, err = detachIp(client, auth, instanceId, ip.Address)
+	} else {
+		ticket, _, err = detachIpById(client, auth, instanceId, ip.Id)
+	}
+	if err != nil {
+		return fmt.Errorf("can't detach IP %v from instance %v. %s", ip.Id, instanceId, err)
+	}
+	if ticket.Status.Id != DICT_TICKET_SUCCEED {
+		return fmt.Errorf("can't detach IP %v from instance %v. Ticket status=%v", ip.Id, instanceId, ticket.Status.Id)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package oktawave
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/oktawave-code/odk"
+)
+
+func testAccIpAttachmentConfig(instanceName string) string {
+	return fmt.Sprintf(`
+resource "oktawave_ip" "test-ip1" {
+	subregion_id = 1
+}
+
+resource "oktawave_instance" "test-instance1" {
+	name = "test-instance1"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+}
+
+resource "oktawave_instance" "test-instance2" {
+	name = "test-instance2"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+}
+
+resource "oktawave_ip_attachment" "test-attachment" {
+	ip_id = oktawave_ip.test-ip1.id
+	instance_id = oktawave_instance.%s.id
+}
+`, instanceName)
+}
+
+func TestAccOktawaveIpAttachment_Basic(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccIpAttachmentConfig("test-instance1"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair("oktawave_ip_attachment.test-attachment", "ip_id", "oktawave_ip.test-ip1", "id"),
+					resource.TestCheckResourceAttrPair("oktawave_ip_attachment.test-attachment", "instance_id", "oktawave_instance.test-instance1", "id"),
+				),
+			},
+			{
+				Config: testAccIpAttachmentConfig("test-instance2"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair("oktawave_ip_attachment.test-attachment", "instance_id", "oktawave_instance.test-instance2", "id"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceIpAttachmentMove(t *testing.T) {
+	var requests []string
+	instanceId := int32(5)
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/instances/ip_addresses/11":
+			testWriteJson(t, w, odk.Ip{Id: 11, Instance: &odk.BaseResource{Id: instanceId}})
+		case r.Method == http.MethodPost:
+			if r.URL.Path == "/instances/6/attach_ip_ticket" {
+				instanceId = 6
+			}
+			testWriteJson(t, w, odk.Ticket{Id: 1, EndDate: time.Now(), Status: &odk.DictionaryItem{Id: DICT_TICKET_SUCCEED}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	state := &terraform.InstanceState{
+		ID:         "11",
+		Attributes: map[string]string{"ip_id": "11", "instance_id": "5"},
+	}
+	d, err := schema.InternalMap(resourceIpAttachment().Schema).Data(state, &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"instance_id": {Old: "5", New: "6"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceIpAttachmentUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	expected := []string{
+		"GET /instances/ip_addresses/11",
+		"POST /instances/5/detach_ip_ticket",
+		"POST /instances/6/attach_ip_ticket",
+		"GET /instances/ip_addresses/11",
+	}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+	if d.Get("instance_id").(int) != 6 {
+		t.Errorf("expected IP to be attached to instance 6, got %v", d.Get("instance_id"))
+	}
+
+	// attachment changed outside of terraform
+	instanceId = 7
+	if diags := resourceIpAttachmentRead(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	if d.Get("instance_id").(int) != 7 {
+		t.Errorf("expected drift to instance 7 to be detected, got %v", d.Get("instance_id"))
+	}
+}
+
+func TestResourceIpAttachmentMoveFloatingIp(t *testing.T) {
+	var requests []string
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/instances/ip_addresses/11":
+			testWriteJson(t, w, odk.Ip{Id: 11, Address: "192.0.2.10", Mode: &odk.DictionaryItem{Id: DICT_IP_MODE_FLOATING}, Instance: &odk.BaseResource{Id: 5}})
+		case r.Method == http.MethodPost:
+			testWriteJson(t, w, odk.Ticket{Id: 1, EndDate: time.Now(), Status: &odk.DictionaryItem{Id: DICT_TICKET_SUCCEED}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	state := &terraform.InstanceState{
+		ID:         "11",
+		Attributes: map[string]string{"ip_id": "11", "instance_id": "5"},
+	}
+	d, err := schema.InternalMap(resourceIpAttachment().Schema).Data(state, &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"instance_id": {Old: "5", New: "6"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceIpAttachmentUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	expected := []string{
+		"GET /instances/ip_addresses/11?",
+		"POST /floating_ips/detach_ip_ticket?instanceId=5&ipV4=192.0.2.10",
+		"POST /floating_ips/attach_ip_ticket?instanceId=6&ipV4=192.0.2.10",
+		"GET /instances/ip_addresses/11?",
+	}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) {
+		t.Errorf("expected floating IP tickets %v, got %v", expected, requests)
+	}
+}
+
+func TestResourceIpAttachmentDeleteChecksCurrentState(t *testing.T) {
+	cases := map[string]struct {
+		ip       *odk.Ip
+		expected []string
+	}{
+		"moved": {
+			ip:       &odk.Ip{Id: 11, Instance: &odk.BaseResource{Id: 7}},
+			expected: []string{"GET /instances/ip_addresses/11", "POST /instances/7/detach_ip_ticket"},
+		},
+		"detached": {
+			ip:       &odk.Ip{Id: 11},
+			expected: []string{"GET /instances/ip_addresses/11"},
+		},
+		"released": {
+			expected: []string{"GET /instances/ip_addresses/11"},
+		},
+	}
+	for name, c := range cases {
+		var requests []string
+		config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/instances/ip_addresses/11":
+				if c.ip == nil {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				testWriteJson(t, w, c.ip)
+			case r.Method == http.MethodPost:
+				testWriteJson(t, w, odk.Ticket{Id: 1, EndDate: time.Now(), Status: &odk.DictionaryItem{Id: DICT_TICKET_SUCCEED}})
+			default:
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+
+		d := schema.TestResourceDataRaw(t, resourceIpAttachment().Schema, map[string]interface{}{"ip_id": 11, "instance_id": 5})
+		d.SetId("11")
+		if diags := resourceIpAttachmentDelete(context.Background(), d, config); diags.HasError() {
+			t.Errorf("%s: unexpected error %v", name, diags)
+		}
+		if fmt.Sprint(requests) != fmt.Sprint(c.expected) {
+			t.Errorf("%s: expected requests %v, got %v", name, c.expected, requests)
+		}
+	}
+}
+
+func TestAttachInstanceToIpsByMode(t *testing.T) {
+	var requests []string
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/instances/ip_addresses/11":
+			testWriteJson(t, w, odk.Ip{Id: 11, Address: "192.0.2.11"})
+		case r.Method == http.MethodGet && r.URL.Path == "/instances/ip_addresses/12":
+			testWriteJson(t, w, odk.Ip{Id: 12, Address: "192.0.2.12", Mode: &odk.DictionaryItem{Id: DICT_IP_MODE_FLOATING}})
+		case r.Method == http.MethodPost:
+			testWriteJson(t, w, odk.Ticket{Id: 1, EndDate: time.Now(), Status: &odk.DictionaryItem{Id: DICT_TICKET_SUCCEED}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	if err := attachInstanceToIps(config.odkClient, config.odkAuth, []int32{11, 12}, 5); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := detachInstanceFromIps(config.odkClient, config.odkAuth, []int32{11, 12}, 5); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []string{
+		"GET /instances/ip_addresses/11?",
+		"POST /instances/5/attach_ip_ticket?ipId=11",
+		"GET /instances/ip_addresses/12?",
+		"POST /floating_ips/attach_ip_ticket?instanceId=5&ipV4=192.0.2.12",
+		"GET /instances/ip_addresses/11?",
+		"POST /instances/5/detach_ip_ticket?ipId=11",
+		"GET /instances/ip_addresses/12?",
+		"POST /floating_ips/detach_ip_ticket?instanceId=5&ipV4=192.0.2.12",
+	}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+}
@@ -0,0 +1,343 @@
+package oktawave
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/oktawave-code/odk"
+)
+
+func resourceIpPool() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceIpPoolCreate,
+		ReadContext:   resourceIpPoolRead,
+		UpdateContext: resourceIpPoolUpdate,
+		DeleteContext: resourceIpPoolDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceIpPoolImport,
+		},
+		Schema: map[string]*schema.Schema{
+			// Required
+			"comment_prefix": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Prefix of pool members comments, members are commented as <prefix>-<number>. It is also used as pool id.",
+			},
+			"ips_count": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Number of IPs in pool. Pool is scaled in and out when this value changes. Scaling in releases unattached IPs with highest comment numbers and fails if there are not enough of them.",
+			},
+			"subregion_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "ID from subregions resource.",
+			},
+			// Optional
+			"deletion_protection": deletionProtectionSchema(),
+			// Computed
+			"ip_ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeInt,
+				},
+				Description: "Ids of pool members, ordered by id.",
+			},
+			"addresses": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "IPv4 addresses of pool members, in ip_ids order.",
+			},
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(45 * time.Minute),
+		},
+		Description: "Pool of public IPs booked in one subregion and scaled by changing ips_count.",
+	}
+}
+
+func resourceIpPoolCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "creating ip pool")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	d.SetId(d.Get("comment_prefix").(string))
+
+	members, err := bookIpPoolMembers(ctx, client, auth, d, nil, d.Get("ips_count").(int))
+	if d.Set("ip_ids", members) != nil {
+		return diag.Errorf("Can't store pool members")
+	}
+	if err != nil {
+		if len(members) == 0 {
+			d.SetId("")
+		}
+		return diag.Errorf("Unable to create IP pool. %s", err)
+	}
+
+	return resourceIpPoolRead(ctx, d, m)
+}
+
+func resourceIpPoolRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "reading ip pool")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	ips, err := getIpPoolMembers(ctx, client, auth, castToInt32(d.Get("ip_ids").([]interface{})))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return loadIpPoolData(ctx, d, m, ips)
+}
+
+func resourceIpPoolUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "updating ip pool")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	if d.HasChange("ips_count") {
+		members := castToInt32(d.Get("ip_ids").([]interface{}))
+		wanted := d.Get("ips_count").(int)
+		tflog.Info(ctx, fmt.Sprintf("pool size change detected (%d -> %d)", len(members), wanted))
+
+		if wanted > len(members) {
+			var err error
+			members, err = bookIpPoolMembers(ctx, client, auth, d, members, wanted-len(members))
+			if d.Set("ip_ids", members) != nil {
+				return diag.Errorf("Can't store pool members")
+			}
+			if err != nil {
+				return diag.Errorf("Unable to scale out IP pool. %s", err)
+			}
+		}
+
+		if wanted < len(members) {
+			ips, err := getIpPoolMembers(ctx, client, auth, members)
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			err = releaseIpPoolMembers(ctx, client, auth, d, ips, len(ips)-wanted)
+			m.(*ClientConfig).ipCache.invalidate()
+			if err != nil {
+				return diag.Errorf("Unable to scale in IP pool. %s", err)
+			}
+		}
+	}
+
+	return resourceIpPoolRead(ctx, d, m)
+}
+
+func resourceIpPoolDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "deleting ip pool")
+
+	if diags := checkDeletionProtection(d); diags != nil {
+		return diags
+	}
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	ips, err := getIpPoolMembers(ctx, client, auth, castToInt32(d.Get("ip_ids").([]interface{})))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = releaseIpPoolMembers(ctx, client, auth, d, ips, len(ips))
+	m.(*ClientConfig).ipCache.invalidate()
+	if err != nil {
+		return diag.Errorf("Unable to delete IP pool. %s", err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+func resourceIpPoolImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	// pool is identified by comment prefix, members are found by <prefix>-<n> comments
+	ips, err := findIpPoolMembers(ctx, client, auth, d.Id())
+	if err != nil {
+		return nil, err
+	}
+	memberIds := make([]int32, len(ips))
+	for i, ip := range ips {
+		memberIds[i] = ip.Id
+	}
+	if len(memberIds) == 0 {
+		return nil, fmt.Errorf("no IPs commented %s-<n> found", d.Id())
+	}
+	if err := d.Set("comment_prefix", d.Id()); err != nil {
+		return nil, err
+	}
+	if err := d.Set("ip_ids", memberIds); err != nil {
+		return nil, err
+	}
+	return importStatePassthroughWithDeletionProtection(ctx, d, m)
+}
+
+func loadIpPoolData(ctx context.Context, d *schema.ResourceData, m interface{}, ips []odk.Ip) diag.Diagnostics {
+	ipIds := make([]int, len(ips))
+	addresses := make([]string, len(ips))
+	for i, ip := range ips {
+		ipIds[i] = int(ip.Id)
+		addresses[i] = ip.Address
+	}
+
+	// Store everything
+	tflog.Debug(ctx, "Parsing returned data")
+	if d.Set("ip_ids", ipIds) != nil {
+		return diag.Errorf("Can't retrieve pool members")
+	}
+	if d.Set("ips_count", len(ips)) != nil {
+		return diag.Errorf("Can't retrieve IPs count")
+	}
+	if d.Set("addresses", addresses) != nil {
+		return diag.Errorf("Can't retrieve addresses")
+	}
+	if len(ips) > 0 && ips[0].Subregion != nil {
+		if d.Set("subregion_id", ips[0].Subregion.Id) != nil {
+			return diag.Errorf("Can't retrieve subregion id")
+		}
+	}
+	return nil
+}
+
+// getIpPoolMembers returns existing pool members ordered by id. Members removed outside of terraform are skipped.
+func getIpPoolMembers(ctx context.Context, client odk.APIClient, auth *context.Context, memberIds []int32) ([]odk.Ip, error) {
+	ips := make([]odk.Ip, 0, len(memberIds))
+	for _, memberId := range memberIds {
+		tflog.Debug(ctx, "calling ODK OCIInterfacesApi.InstancesGetInstanceIp", map[string]interface{}{"id": memberId})
+		ip, resp, err := client.OCIInterfacesApi.InstancesGetInstanceIp(*auth, memberId, nil)
+		if err != nil {
+			if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+				tflog.Warn(ctx, fmt.Sprintf("Pool member %v no longer exists", memberId))
+				continue
+			}
+			return nil, fmt.Errorf("error while retrieving pool member %v: %s", memberId, err)
+		}
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool { return ips[i].Id < ips[j].Id })
+	return ips, nil
+}
+
+// bookIpPoolMembers books count IPs one by one and returns ids of all pool members.
+// IPs are commented <prefix>-<n>, numbers of released members are not reused.
+func bookIpPoolMembers(ctx context.Context, client odk.APIClient, auth *context.Context, d *schema.ResourceData, members []int32, count int) ([]int32, error) {
+	commentPrefix := d.Get("comment_prefix").(string)
+	existing, err := findIpPoolMembers(ctx, client, auth, commentPrefix)
+	if err != nil {
+		return members, err
+	}
+	nextIndex := 1
+	for _, ip := range existing {
+		if index, _ := instancePoolMemberIndex(ip.Comment, commentPrefix); index >= nextIndex {
+			nextIndex = index + 1
+		}
+	}
+
+	bookCommand := odk.BookIpCommand{
+		SubregionId: int32(d.Get("subregion_id").(int)),
+	}
+	for i := 0; i < count; i++ {
+		tflog.Debug(ctx, "calling ODK OCIInterfacesApi.InstancesBookNewIp")
+		ip, _, err := client.OCIInterfacesApi.InstancesBookNewIp(*auth, bookCommand)
+		if err != nil {
+			return members, fmt.Errorf("ODK Error in OCIInterfacesApi.InstancesBookNewIp. %s", err)
+		}
+		members = append(members, ip.Id)
+		tflog.Info(ctx, fmt.Sprintf("booked pool member. id=%v", ip.Id))
+
+		updateCommand := odk.UpdateIpCommand{
+			Comment: fmt.Sprintf("%s-%d", commentPrefix, nextIndex+i),
+		}
+		tflog.Debug(ctx, "calling ODK OCIInterfacesApi.InstancesUpdateIp", map[string]interface{}{"id": ip.Id})
+		_, _, err = client.OCIInterfacesApi.InstancesUpdateIp(*auth, ip.Id, updateCommand)
+		if err != nil && err.Error() != "EOF" { // "EOF" condition is a patch for ODK 1.4 bug: it reports error when API returns empty body
+			return members, fmt.Errorf("ODK Error in OCIInterfacesApi.InstancesUpdateIp. %s", err)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i] < members[j] })
+	return members, nil
+}
+
+// findIpPoolMembers lists IPs commented <prefix>-<n> ordered by id, including ones not tracked in state.
+func findIpPoolMembers(ctx context.Context, client odk.APIClient, auth *context.Context, commentPrefix string) ([]odk.Ip, error) {
+	tflog.Debug(ctx, "calling ODK OCIInterfacesApi.InstancesGetIps")
+	params := map[string]interface{}{
+		"pageSize": int32(math.MaxInt16),
+	}
+	list, _, err := client.OCIInterfacesApi.InstancesGetIps(*auth, params)
+	if err != nil {
+		return nil, fmt.Errorf("ODK Error in OCIInterfacesApi.InstancesGetIps. %s", err)
+	}
+	ips := make([]odk.Ip, 0)
+	for _, ip := range list.Items {
+		if isInstancePoolMemberName(ip.Comment, commentPrefix) {
+			ips = append(ips, ip)
+		}
+	}
+	sort.Slice(ips, func(i, j int) bool { return ips[i].Id < ips[j].Id })
+	return ips, nil
+}
+
+// releaseIpPoolMembers releases count unattached pool members with highest <prefix>-<n> numbers.
+// Nothing is released when there are not enough of them.
+func releaseIpPoolMembers(ctx context.Context, client odk.APIClient, auth *context.Context, d *schema.ResourceData, ips []odk.Ip, count int) error {
+	commentPrefix := d.Get("comment_prefix").(string)
+	candidates := make([]odk.Ip, len(ips))
+	copy(candidates, ips)
+	// members with changed comments have no number and are released last
+	sort.SliceStable(candidates, func(i, j int) bool {
+		indexI, _ := instancePoolMemberIndex(candidates[i].Comment, commentPrefix)
+		indexJ, _ := instancePoolMemberIndex(candidates[j].Comment, commentPrefix)
+		return indexI < indexJ
+	})
+
+	var released []odk.Ip
+	var attached []int32
+	for i := len(candidates) - 1; i >= 0 && len(released) < count; i-- {
+		if candidates[i].Instance != nil {
+			attached = append(attached, candidates[i].Id)
+			continue
+		}
+		released = append(released, candidates[i])
+	}
+	if len(released) < count {
+		return fmt.Errorf("only %d of %d IPs can be released, IPs %v are attached to instances. Detach them first", len(released), count, attached)
+	}
+
+	members := make([]int32, len(ips))
+	for i, ip := range ips {
+		members[i] = ip.Id
+	}
+	for _, ip := range released {
+		if err := releaseIp(ctx, client, auth, ip); err != nil {
+			return err
+		}
+		members = calcListAMinusListB(members, []int32{ip.Id})
+		if d.Set("ip_ids", members) != nil {
+			return fmt.Errorf("can't store pool members")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package oktawave
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/oktawave-code/odk"
+)
+
+func testAccIpPoolConfig(count int) string {
+	return fmt.Sprintf(`
+resource "oktawave_ip_pool" "test-pool" {
+	comment_prefix = "test-ip-pool"
+	subregion_id = 1
+	ips_count = %d
+}
+`, count)
+}
+
+func TestAccOktawaveIpPool_Basic(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckIpPoolDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccIpPoolConfig(2),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_ip_pool.test-pool", "id", "test-ip-pool"),
+					resource.TestCheckResourceAttr("oktawave_ip_pool.test-pool", "ip_ids.#", "2"),
+					resource.TestCheckResourceAttr("oktawave_ip_pool.test-pool", "addresses.#", "2"),
+				),
+			},
+			{
+				Config: testAccIpPoolConfig(3),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_ip_pool.test-pool", "ip_ids.#", "3"),
+					resource.TestCheckResourceAttr("oktawave_ip_pool.test-pool", "addresses.#", "3"),
+				),
+			},
+			{
+				Config: testAccIpPoolConfig(1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_ip_pool.test-pool", "ip_ids.#", "1"),
+					resource.TestCheckResourceAttr("oktawave_ip_pool.test-pool", "addresses.#", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceIpPoolScaleInKeepsAttachedIps(t *testing.T) {
+	var deleted []string
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			ip := odk.Ip{Subregion: &odk.BaseResource{Id: 1}}
+			fmt.Sscanf(r.URL.Path, "/instances/ip_addresses/%d", &ip.Id)
+			// ids of booked IPs don't have to follow comment numbers
+			ip.Comment = map[int32]string{11: "pool-1", 12: "pool-2", 13: "pool-3", 21: "pool-10", 22: "pool-9"}[ip.Id]
+			if ip.Id == 13 {
+				ip.Instance = &odk.BaseResource{Id: 5}
+			}
+			testWriteJson(t, w, ip)
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			testWriteJson(t, w, odk.Object{})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	ips, err := getIpPoolMembers(context.Background(), config.odkClient, config.odkAuth, []int32{13, 11, 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceIpPool().Schema, map[string]interface{}{"comment_prefix": "pool"})
+	if err := releaseIpPoolMembers(context.Background(), config.odkClient, config.odkAuth, d, ips, 3); err == nil {
+		t.Errorf("expected attached IP to block scaling in")
+	}
+	if len(deleted) != 0 {
+		t.Errorf("expected nothing to be released, got %v", deleted)
+	}
+
+	if err := releaseIpPoolMembers(context.Background(), config.odkClient, config.odkAuth, d, ips, 1); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if fmt.Sprint(deleted) != "[/instances/ip_addresses/12]" {
+		t.Errorf("expected newest unattached IP 12 to be released, got %v", deleted)
+	}
+	if fmt.Sprint(d.Get("ip_ids")) != "[11 13]" {
+		t.Errorf("expected remaining members [11 13], got %v", d.Get("ip_ids"))
+	}
+
+	deleted = nil
+	ips, err = getIpPoolMembers(context.Background(), config.odkClient, config.odkAuth, []int32{11, 21, 22})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := releaseIpPoolMembers(context.Background(), config.odkClient, config.odkAuth, d, ips, 1); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if fmt.Sprint(deleted) != "[/instances/ip_addresses/21]" {
+		t.Errorf("expected IP 21 with highest comment number to be released, got %v", deleted)
+	}
+	if fmt.Sprint(d.Get("ip_ids")) != "[11 22]" {
+		t.Errorf("expected remaining members [11 22], got %v", d.Get("ip_ids"))
+	}
+}
+
+func TestResourceIpPoolScaleOutNumbersComments(t *testing.T) {
+	var comments []string
+	booked := int32(20)
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/instances/ip_addresses":
+			testWriteJson(t, w, odk.ApiCollectionIp{Items: []odk.Ip{
+				{Id: 11, Comment: "pool-1"},
+				{Id: 14, Comment: "pool-4"},
+				{Id: 15, Comment: "pool7"},
+				{Id: 16, Comment: "other-pool-9"},
+			}})
+		case r.Method == http.MethodPost && r.URL.Path == "/instances/ip_addresses":
+			booked++
+			testWriteJson(t, w, odk.Ip{Id: booked})
+		case r.Method == http.MethodPut:
+			var updateCommand odk.UpdateIpCommand
+			if err := json.NewDecoder(r.Body).Decode(&updateCommand); err != nil {
+				t.Errorf("can't decode IP update. %s", err)
+			}
+			comments = append(comments, updateCommand.Comment)
+			testWriteJson(t, w, odk.Object{})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	d := schema.TestResourceDataRaw(t, resourceIpPool().Schema, map[string]interface{}{"comment_prefix": "pool"})
+	members, err := bookIpPoolMembers(context.Background(), config.odkClient, config.odkAuth, d, []int32{11}, 2)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if fmt.Sprint(comments) != "[pool-5 pool-6]" {
+		t.Errorf("expected comments numbered after highest existing member, got %v", comments)
+	}
+	if fmt.Sprint(members) != "[11 21 22]" {
+		t.Errorf("expected members [11 21 22], got %v", members)
+	}
+}
+
+func testAccCheckIpPoolDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(*ClientConfig).odkClient
+	auth := testAccProvider.Meta().(*ClientConfig).odkAuth
+
+	params := map[string]interface{}{
+		"pageSize": int32(math.MaxInt16),
+	}
+	ips, _, err := client.OCIInterfacesApi.InstancesGetIps(*auth, params)
+	if err != nil {
+		return fmt.Errorf("Get ips request failed. Caused by: %s.", err)
+	}
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "oktawave_ip_pool" {
+			continue
+		}
+		for _, ip := range ips.Items {
+			if isInstancePoolMemberName(ip.Comment, rs.Primary.ID) {
+				return fmt.Errorf("Ip with id %d from pool %s not destroyed correctly.", ip.Id, rs.Primary.ID)
+			}
+		}
+	}
+	return nil
+}
@@ -1,11 +1,17 @@
 package oktawave
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"regexp"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/oktawave-code/odk"
 )
@@ -50,6 +56,45 @@ resource "oktawave_ip" "test-ip" {
 	})
 }
 
+func TestAccOktawaveIp_DeletionProtection(t *testing.T) {
+	protectedConfig := `
+resource "oktawave_ip" "test-ip" {
+	subregion_id = 1
+	deletion_protection = true
+}
+`
+	unprotectedConfig := `
+resource "oktawave_ip" "test-ip" {
+	subregion_id = 1
+	deletion_protection = false
+}
+`
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckIpDatasourceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: protectedConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_ip.test-ip", "deletion_protection", "true"),
+				),
+			},
+			{
+				Config:      protectedConfig,
+				Destroy:     true,
+				ExpectError: regexp.MustCompile("protected by deletion_protection"),
+			},
+			{
+				Config: unprotectedConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_ip.test-ip", "deletion_protection", "false"),
+				),
+			},
+		},
+	})
+}
+
 func TestAccOktawaveIp_Update(t *testing.T) {
 	var ip odk.Ip
 
@@ -138,6 +183,138 @@ resource "oktawave_instance" "test-instance" {
 	})
 }
 
+type testStubResolver map[string][]string
+
+func (r testStubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addresses, ok := r[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	return addresses, nil
+}
+
+func TestValidateHostname(t *testing.T) {
+	valid := []string{"", "example.com", "host-1.example.com.", "a", "xn--80ak6aa92e.com"}
+	invalid := []string{"-host.example.com", "host-.example.com", "host..example.com", "host_1.example.com", "example.com..", "host example.com"}
+	for _, hostname := range valid {
+		if _, errs := validateHostname(hostname, "rev_dns"); len(errs) > 0 {
+			t.Errorf("expected %q to be valid, got %v", hostname, errs)
+		}
+	}
+	for _, hostname := range invalid {
+		if _, errs := validateHostname(hostname, "rev_dns"); len(errs) == 0 {
+			t.Errorf("expected %q to be invalid", hostname)
+		}
+	}
+}
+
+func TestVerifyRevDns(t *testing.T) {
+	config := &ClientConfig{resolver: testStubResolver{
+		"host.example.com":  {"192.0.2.10", "2001:db8::10"},
+		"other.example.com": {"192.0.2.20"},
+	}}
+	cases := []struct {
+		hostname string
+		address  string
+		valid    bool
+	}{
+		{"host.example.com", "192.0.2.10", true},
+		{"host.example.com", "2001:db8:0::10", true},
+		{"other.example.com", "192.0.2.10", false},
+		{"missing.example.com", "192.0.2.10", false},
+		{"", "192.0.2.10", true},
+	}
+	for _, c := range cases {
+		err := verifyRevDns(context.Background(), config, c.hostname, c.address)
+		if (err == nil) != c.valid {
+			t.Errorf("%q -> %s: expected valid=%v, got error %v", c.hostname, c.address, c.valid, err)
+		}
+	}
+}
+
+func TestResourceIpAddressDeleteRestoresRevDns(t *testing.T) {
+	var requests []string
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, body))
+		switch r.Method {
+		case http.MethodGet:
+			testWriteJson(t, w, odk.Ip{Id: 11, Address: "192.0.2.10", RevDns: "host.example.com"})
+		case http.MethodPut:
+			testWriteJson(t, w, odk.Ip{Id: 11})
+		case http.MethodDelete:
+			testWriteJson(t, w, odk.Object{})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	d := schema.TestResourceDataRaw(t, resourceIpAddress().Schema, map[string]interface{}{})
+	d.SetId("11")
+	if diags := resourceIpAddressDelete(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	if len(requests) != 3 || !regexp.MustCompile(`^PUT .*"RestoreRevDns":true`).MatchString(requests[1]) || regexp.MustCompile("RestoreRevDnsV6").MatchString(requests[1]) {
+		t.Errorf("expected rev DNS to be restored before delete, got %v", requests)
+	}
+}
+
+func TestResourceIpAddressDeleteInvalidatesIpCache(t *testing.T) {
+	config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/instances/ip_addresses/11":
+			testWriteJson(t, w, odk.Ip{Id: 11, Address: "192.0.2.10", Instance: &odk.BaseResource{Id: 5}})
+		case r.Method == http.MethodPost && r.URL.Path == "/instances/5/detach_ip_ticket":
+			testWriteJson(t, w, odk.Ticket{Id: 1, EndDate: time.Now(), Status: &odk.DictionaryItem{Id: DICT_TICKET_SUCCEED}})
+		case r.Method == http.MethodDelete:
+			testWriteJson(t, w, odk.Object{})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	config.ipCache.loaded = true
+
+	d := schema.TestResourceDataRaw(t, resourceIpAddress().Schema, map[string]interface{}{})
+	d.SetId("11")
+	if diags := resourceIpAddressDelete(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("unexpected error %v", diags)
+	}
+	if config.ipCache.loaded {
+		t.Errorf("expected IP list cache to be invalidated after detaching IP")
+	}
+}
+
+func TestResourceIpAddressCreateBooksByMode(t *testing.T) {
+	for mode, path := range map[int]string{DICT_IP_MODE_NORMAL: "/instances/ip_addresses", DICT_IP_MODE_FLOATING: "/floating_ips"} {
+		var booked []string
+		config := testOdkMockConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodPost:
+				booked = append(booked, r.URL.Path)
+				testWriteJson(t, w, odk.Ip{Id: 11, Address: "192.0.2.10"})
+			case r.Method == http.MethodGet && r.URL.Path == "/instances/ip_addresses/11":
+				testWriteJson(t, w, odk.Ip{Id: 11, Address: "192.0.2.10", Subregion: &odk.BaseResource{Id: 1}, Mode: &odk.DictionaryItem{Id: int32(mode)}})
+			default:
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+
+		d := schema.TestResourceDataRaw(t, resourceIpAddress().Schema, map[string]interface{}{"subregion_id": 1, "mode_id": mode})
+		if diags := resourceIpAddressCreate(context.Background(), d, config); diags.HasError() {
+			t.Fatalf("unexpected error %v", diags)
+		}
+		if fmt.Sprint(booked) != fmt.Sprint([]string{path}) {
+			t.Errorf("expected IP with mode %v to be booked with %s, got %v", mode, path, booked)
+		}
+		if d.Get("mode_id").(int) != mode {
+			t.Errorf("expected mode %v, got %v", mode, d.Get("mode_id"))
+		}
+	}
+}
+
 func testAccCheckIpExists(resourceName string, ip *odk.Ip) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[resourceName]
@@ -16,11 +16,13 @@ func resourceOksCluster() *schema.Resource {
 	return &schema.Resource{
 		CreateContext: resourceOksClusterCreate,
 		ReadContext:   resourceOksClusterRead,
+		UpdateContext: resourceOksClusterUpdate,
 		DeleteContext: resourceOksClusterDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importStatePassthroughWithDeletionProtection,
 		},
 		Schema: map[string]*schema.Schema{
+			"deletion_protection": deletionProtectionSchema(),
 			"id": {
 				Type:     schema.TypeString,
 				Computed: true,
@@ -94,9 +96,18 @@ func resourceOksClusterRead(ctx context.Context, d *schema.ResourceData, m inter
 	return loadOksClusterData(ctx, d, m, cluster)
 }
 
+// resourceOksClusterUpdate only stores deletion_protection, every other argument forces new cluster.
+func resourceOksClusterUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return resourceOksClusterRead(ctx, d, m)
+}
+
 func resourceOksClusterDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	tflog.Info(ctx, "deleting oks cluster")
 
+	if diags := checkDeletionProtection(d); diags != nil {
+		return diags
+	}
+
 	client := m.(*ClientConfig).oksClient
 	auth := m.(*ClientConfig).oksAuth
 
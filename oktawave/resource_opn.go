@@ -21,9 +21,10 @@ func resourceOpn() *schema.Resource {
 		UpdateContext: resourceOpnUpdate,
 		DeleteContext: resourceOpnDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importStatePassthroughWithDeletionProtection,
 		},
 		Schema: map[string]*schema.Schema{
+			"deletion_protection": deletionProtectionSchema(),
 			"name": {
 				Type:        schema.TypeString,
 				Required:    true,
@@ -147,6 +148,10 @@ func resourceOpnUpdate(ctx context.Context, d *schema.ResourceData, m interface{
 func resourceOpnDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	tflog.Info(ctx, "deleting opn")
 
+	if diags := checkDeletionProtection(d); diags != nil {
+		return diags
+	}
+
 	client := m.(*ClientConfig).odkClient
 	auth := m.(*ClientConfig).odkAuth
 
@@ -0,0 +1,414 @@
+package oktawave
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/oktawave-code/odk"
+)
+
+func resourceScheduler() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceSchedulerCreate,
+		ReadContext:   resourceSchedulerRead,
+		UpdateContext: resourceSchedulerUpdate,
+		DeleteContext: resourceSchedulerDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceSchedulerImport,
+		},
+		Schema: map[string]*schema.Schema{
+			// Required
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Scheduler name.",
+			},
+			"instance_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Id of instance the operation is executed on. For group schedulers it must be a member of group.",
+			},
+			"action_type_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "Executed operation, e.g. power on, power off, instance type change or snapshot creation.",
+			},
+			"scheduler_type_id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "Tells if scheduler runs once or repeatedly.",
+			},
+			"start_date": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateFunc:     validation.IsRFC3339Time,
+				DiffSuppressFunc: suppressEqualTimes,
+				Description:      "Date of first run in RFC 3339 format, e.g. 2024-01-01T08:00:00+01:00.",
+			},
+			// Optional
+			"group_id": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Creates group scheduler for this group instead of instance scheduler.",
+			},
+			"time_zone_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "UTC",
+				Description: "IANA time zone name used to compute following runs, e.g. Europe/Warsaw.",
+			},
+			"cycle_type_id": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Unit of interval between runs of repeated scheduler, e.g. day or week.",
+			},
+			"cycle_number": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Number of cycle units between runs of repeated scheduler.",
+			},
+			"new_instance_type_id": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				ConflictsWith: []string{"group_id"},
+				Description:   "Instance type set by instance type change action. Value from dictionary #12",
+			},
+			"snapshot_name": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"group_id"},
+				Description:   "Name of snapshot taken by snapshot creation action.",
+			},
+			"snapshot_description": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"group_id"},
+				Description:   "Description of snapshot taken by snapshot creation action.",
+			},
+			// Computed
+			"status_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Scheduler status.",
+			},
+			"creation_date": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Date when scheduler was created.",
+			},
+			"last_change_date": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Date of last scheduler change.",
+			},
+			"creation_user_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Id of user who created scheduler.",
+			},
+		},
+		Description: "Recurring or one-time operation executed on instance or group. Schedule is defined by start date and optional cycle, as supported by Oktawave API. Group schedulers are imported with group/<id> id.",
+	}
+}
+
+func resourceSchedulerCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "creating scheduler")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	startDate, err := time.Parse(time.RFC3339, d.Get("start_date").(string))
+	if err != nil {
+		return diag.Errorf("Invalid start date. %s", err)
+	}
+
+	if groupId, isGroup := d.GetOk("group_id"); isGroup {
+		createCommand := makeGroupSchedulerCommand(d, startDate)
+		tflog.Debug(ctx, "calling ODK OCIGroupsApi.GroupsCreateContainerScheduler", map[string]interface{}{"groupId": groupId})
+		scheduler, _, err := client.OCIGroupsApi.GroupsCreateContainerScheduler(*auth, int32(groupId.(int)), createCommand)
+		if err != nil {
+			return diag.Errorf("ODK Error in OCIGroupsApi.GroupsCreateContainerScheduler. %s", err)
+		}
+		d.SetId(strconv.Itoa(int(scheduler.Id)))
+	} else {
+		createCommand := makeInstanceSchedulerCommand(d, startDate)
+		instanceId := int32(d.Get("instance_id").(int))
+		tflog.Debug(ctx, "calling ODK OCISchedulersApi.InstanceSchedulersPost", map[string]interface{}{"instanceId": instanceId})
+		scheduler, _, err := client.OCISchedulersApi.InstanceSchedulersPost(*auth, instanceId, createCommand)
+		if err != nil {
+			return diag.Errorf("ODK Error in OCISchedulersApi.InstanceSchedulersPost. %s", err)
+		}
+		d.SetId(strconv.Itoa(int(scheduler.Id)))
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("successfully created scheduler. id=%v", d.Id()))
+	return resourceSchedulerRead(ctx, d, m)
+}
+
+func resourceSchedulerRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "reading scheduler")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	schedulerId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.Errorf("Invalid scheduler id: %v %s", d.Id(), err)
+	}
+
+	if _, isGroup := d.GetOk("group_id"); isGroup {
+		tflog.Debug(ctx, "calling ODK OCIGroupsApi.GroupsGetGroupScheduler", map[string]interface{}{"id": schedulerId})
+		scheduler, resp, err := client.OCIGroupsApi.GroupsGetGroupScheduler(*auth, int32(schedulerId), nil)
+		if err != nil {
+			if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+				tflog.Warn(ctx, fmt.Sprintf("Group scheduler %v not found, removing it from state", schedulerId))
+				d.SetId("")
+				return nil
+			}
+			return diag.Errorf("ODK Error in OCIGroupsApi.GroupsGetGroupScheduler. %s", err)
+		}
+		return loadGroupSchedulerData(ctx, d, m, scheduler)
+	}
+
+	tflog.Debug(ctx, "calling ODK OCISchedulersApi.InstanceSchedulersGet", map[string]interface{}{"id": schedulerId})
+	scheduler, resp, err := client.OCISchedulersApi.InstanceSchedulersGet(*auth, int32(schedulerId), nil)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			tflog.Warn(ctx, fmt.Sprintf("Instance scheduler %v not found, removing it from state", schedulerId))
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("ODK Error in OCISchedulersApi.InstanceSchedulersGet. %s", err)
+	}
+	return loadInstanceSchedulerData(ctx, d, m, scheduler)
+}
+
+func resourceSchedulerUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "updating scheduler")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	schedulerId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.Errorf("Invalid scheduler id: %v %s", d.Id(), err)
+	}
+
+	startDate, err := time.Parse(time.RFC3339, d.Get("start_date").(string))
+	if err != nil {
+		return diag.Errorf("Invalid start date. %s", err)
+	}
+
+	if _, isGroup := d.GetOk("group_id"); isGroup {
+		updateCommand := makeGroupSchedulerCommand(d, startDate)
+		tflog.Debug(ctx, "calling ODK OCIGroupsApi.GroupsUpdateGroupScheduler", map[string]interface{}{"id": schedulerId})
+		_, _, err := client.OCIGroupsApi.GroupsUpdateGroupScheduler(*auth, int32(schedulerId), updateCommand)
+		if err != nil {
+			return diag.Errorf("ODK Error in OCIGroupsApi.GroupsUpdateGroupScheduler. %s", err)
+		}
+	} else {
+		updateCommand := makeInstanceSchedulerCommand(d, startDate)
+		tflog.Debug(ctx, "calling ODK OCISchedulersApi.InstanceSchedulersPut", map[string]interface{}{"id": schedulerId})
+		_, _, err := client.OCISchedulersApi.InstanceSchedulersPut(*auth, int32(schedulerId), updateCommand)
+		if err != nil {
+			return diag.Errorf("ODK Error in OCISchedulersApi.InstanceSchedulersPut. %s", err)
+		}
+	}
+
+	return resourceSchedulerRead(ctx, d, m)
+}
+
+func resourceSchedulerDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	tflog.Info(ctx, "deleting scheduler")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	schedulerId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.Errorf("Invalid scheduler id: %v %s", d.Id(), err)
+	}
+
+	if _, isGroup := d.GetOk("group_id"); isGroup {
+		tflog.Debug(ctx, "calling ODK OCIGroupsApi.GroupsDeleteGroupScheduler", map[string]interface{}{"id": schedulerId})
+		_, _, err := client.OCIGroupsApi.GroupsDeleteGroupScheduler(*auth, int32(schedulerId))
+		if err != nil && err.Error() != "EOF" { // "EOF" condition is a patch for ODK 1.4 bug: it reports error when API returns empty body
+			return diag.Errorf("ODK Error in OCIGroupsApi.GroupsDeleteGroupScheduler. %s", err)
+		}
+	} else {
+		tflog.Debug(ctx, "calling ODK OCISchedulersApi.InstanceSchedulersDelete", map[string]interface{}{"id": schedulerId})
+		_, err := client.OCISchedulersApi.InstanceSchedulersDelete(*auth, int32(schedulerId))
+		if err != nil && err.Error() != "EOF" { // "EOF" condition is a patch for ODK 1.4 bug: it reports error when API returns empty body
+			return diag.Errorf("ODK Error in OCISchedulersApi.InstanceSchedulersDelete. %s", err)
+		}
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// resourceSchedulerImport accepts instance scheduler id or group/<id> for group scheduler.
+func resourceSchedulerImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	if !strings.HasPrefix(d.Id(), "group/") {
+		return []*schema.ResourceData{d}, nil
+	}
+	groupSchedulerId := strings.TrimPrefix(d.Id(), "group/")
+
+	client := m.(*ClientConfig).odkClient
+	auth := m.(*ClientConfig).odkAuth
+
+	schedulerId, err := strconv.Atoi(groupSchedulerId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid group scheduler id: %v %s", groupSchedulerId, err)
+	}
+	tflog.Debug(ctx, "calling ODK OCIGroupsApi.GroupsGetGroupScheduler", map[string]interface{}{"id": schedulerId})
+	scheduler, _, err := client.OCIGroupsApi.GroupsGetGroupScheduler(*auth, int32(schedulerId), nil)
+	if err != nil {
+		return nil, fmt.Errorf("ODK Error in OCIGroupsApi.GroupsGetGroupScheduler. %s", err)
+	}
+	if scheduler.Group == nil {
+		return nil, fmt.Errorf("scheduler %v is not a group scheduler", schedulerId)
+	}
+	d.SetId(groupSchedulerId)
+	if d.Set("group_id", scheduler.Group.Id) != nil {
+		return nil, fmt.Errorf("can't set group id")
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+func makeInstanceSchedulerCommand(d *schema.ResourceData, startDate time.Time) odk.CreateUpdateInstanceSchedulerCommand {
+	return odk.CreateUpdateInstanceSchedulerCommand{
+		Name:                d.Get("name").(string),
+		TypeId:              int32(d.Get("scheduler_type_id").(int)),
+		StartDate:           startDate,
+		TimeZoneName:        d.Get("time_zone_name").(string),
+		CycleTypeId:         int32(d.Get("cycle_type_id").(int)),
+		CycleNumber:         int32(d.Get("cycle_number").(int)),
+		ActionTypeId:        int32(d.Get("action_type_id").(int)),
+		NewInstanceTypeId:   int32(d.Get("new_instance_type_id").(int)),
+		SnapshotName:        d.Get("snapshot_name").(string),
+		SnapshotDescription: d.Get("snapshot_description").(string),
+	}
+}
+
+func makeGroupSchedulerCommand(d *schema.ResourceData, startDate time.Time) odk.CreateUpdateGroupSchedulerCommand {
+	return odk.CreateUpdateGroupSchedulerCommand{
+		Name:            d.Get("name").(string),
+		StartDate:       startDate,
+		TimeZoneName:    d.Get("time_zone_name").(string),
+		InstanceId:      int32(d.Get("instance_id").(int)),
+		ActionTypeId:    int32(d.Get("action_type_id").(int)),
+		SchedulerTypeId: int32(d.Get("scheduler_type_id").(int)),
+		CycleTypeId:     int32(d.Get("cycle_type_id").(int)),
+		CycleNumber:     int32(d.Get("cycle_number").(int)),
+	}
+}
+
+func loadInstanceSchedulerData(ctx context.Context, d *schema.ResourceData, m interface{}, scheduler odk.InstanceScheduler) diag.Diagnostics {
+	diags := loadSchedulerCommonData(ctx, d, scheduler.Name, scheduler.Instance, scheduler.Type_, scheduler.ActionType, scheduler.CycleType, scheduler.Status,
+		scheduler.StartDate, scheduler.TimeZoneName, scheduler.CycleNumber, scheduler.CreationDate, scheduler.LastChangeDate, scheduler.CreationUser)
+	if diags != nil {
+		return diags
+	}
+	if scheduler.NewInstanceType != nil {
+		if d.Set("new_instance_type_id", scheduler.NewInstanceType.Id) != nil {
+			return diag.Errorf("Can't retrieve new instance type id")
+		}
+	}
+	if d.Set("snapshot_name", scheduler.SnapshotName) != nil {
+		return diag.Errorf("Can't retrieve snapshot name")
+	}
+	if d.Set("snapshot_description", scheduler.SnapshotDescription) != nil {
+		return diag.Errorf("Can't retrieve snapshot description")
+	}
+	return nil
+}
+
+func loadGroupSchedulerData(ctx context.Context, d *schema.ResourceData, m interface{}, scheduler odk.GroupScheduler) diag.Diagnostics {
+	if scheduler.Group != nil {
+		if d.Set("group_id", scheduler.Group.Id) != nil {
+			return diag.Errorf("Can't retrieve group id")
+		}
+	}
+	return loadSchedulerCommonData(ctx, d, scheduler.Name, scheduler.Instance, scheduler.Type_, scheduler.ActionType, scheduler.CycleType, scheduler.Status,
+		scheduler.StartDate, scheduler.TimeZoneName, scheduler.CycleNumber, scheduler.CreationDate, scheduler.LastChangeDate, scheduler.CreationUser)
+}
+
+func loadSchedulerCommonData(ctx context.Context, d *schema.ResourceData, name string, instance *odk.BaseResource, schedulerType *odk.DictionaryItem,
+	actionType *odk.DictionaryItem, cycleType *odk.DictionaryItem, status *odk.DictionaryItem, startDate time.Time, timeZoneName string,
+	cycleNumber int32, creationDate time.Time, lastChangeDate time.Time, creationUser *odk.UserResource) diag.Diagnostics {
+	// Store everything
+	tflog.Debug(ctx, "Parsing returned data")
+	if d.Set("name", name) != nil {
+		return diag.Errorf("Can't retrieve scheduler name")
+	}
+	if instance != nil {
+		if d.Set("instance_id", instance.Id) != nil {
+			return diag.Errorf("Can't retrieve instance id")
+		}
+	}
+	if schedulerType != nil {
+		if d.Set("scheduler_type_id", schedulerType.Id) != nil {
+			return diag.Errorf("Can't retrieve scheduler type id")
+		}
+	}
+	if actionType != nil {
+		if d.Set("action_type_id", actionType.Id) != nil {
+			return diag.Errorf("Can't retrieve action type id")
+		}
+	}
+	if cycleType != nil {
+		if d.Set("cycle_type_id", cycleType.Id) != nil {
+			return diag.Errorf("Can't retrieve cycle type id")
+		}
+	}
+	if status != nil {
+		if d.Set("status_id", status.Id) != nil {
+			return diag.Errorf("Can't retrieve status id")
+		}
+	}
+	if d.Set("start_date", startDate.Format(time.RFC3339)) != nil {
+		return diag.Errorf("Can't retrieve start date")
+	}
+	if d.Set("time_zone_name", timeZoneName) != nil {
+		return diag.Errorf("Can't retrieve time zone name")
+	}
+	if d.Set("cycle_number", cycleNumber) != nil {
+		return diag.Errorf("Can't retrieve cycle number")
+	}
+	if d.Set("creation_date", creationDate.String()) != nil {
+		return diag.Errorf("Can't retrieve creation date")
+	}
+	if d.Set("last_change_date", lastChangeDate.String()) != nil {
+		return diag.Errorf("Can't retrieve last change date")
+	}
+	if creationUser != nil {
+		if d.Set("creation_user_id", creationUser.Id) != nil {
+			return diag.Errorf("Can't retrieve creation user id")
+		}
+	}
+	return nil
+}
+
+// suppressEqualTimes hides difference between two RFC 3339 representations of the same moment.
+func suppressEqualTimes(k, old, new string, d *schema.ResourceData) bool {
+	oldTime, err := time.Parse(time.RFC3339, old)
+	if err != nil {
+		return false
+	}
+	newTime, err := time.Parse(time.RFC3339, new)
+	if err != nil {
+		return false
+	}
+	return oldTime.Equal(newTime)
+}
@@ -0,0 +1,94 @@
+package oktawave
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+// Scheduler dictionary values used by tests. They are not exposed by ODK, verify them against dictionaries of tested account.
+const (
+	testSchedulerTypeCyclic     = 1393
+	testSchedulerActionPowerOff = 1396
+	testSchedulerCycleDay       = 1400
+)
+
+func testAccSchedulerConfig(schedulerName string, startDate string) string {
+	return fmt.Sprintf(`
+resource "oktawave_instance" "test-instance" {
+	name = "test-scheduler-instance"
+	subregion_id = 1
+	system_disk_class_id = 48
+	template_id = 1021
+	type_id = 1047
+	without_public_ip = true
+}
+
+resource "oktawave_scheduler" "test-scheduler" {
+	name = "%s"
+	instance_id = oktawave_instance.test-instance.id
+	scheduler_type_id = %d
+	action_type_id = %d
+	start_date = "%s"
+	time_zone_name = "Europe/Warsaw"
+	cycle_type_id = %d
+	cycle_number = 1
+}
+`, schedulerName, testSchedulerTypeCyclic, testSchedulerActionPowerOff, startDate, testSchedulerCycleDay)
+}
+
+func TestAccOktawaveScheduler_Basic(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckSchedulerDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccSchedulerConfig("test-scheduler", "2030-01-01T18:00:00Z"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair("oktawave_scheduler.test-scheduler", "instance_id", "oktawave_instance.test-instance", "id"),
+					resource.TestCheckResourceAttr("oktawave_scheduler.test-scheduler", "name", "test-scheduler"),
+					resource.TestCheckResourceAttr("oktawave_scheduler.test-scheduler", "action_type_id", strconv.Itoa(testSchedulerActionPowerOff)),
+					resource.TestCheckResourceAttr("oktawave_scheduler.test-scheduler", "time_zone_name", "Europe/Warsaw"),
+					resource.TestCheckResourceAttr("oktawave_scheduler.test-scheduler", "cycle_number", "1"),
+					resource.TestCheckResourceAttrSet("oktawave_scheduler.test-scheduler", "status_id"),
+					resource.TestCheckResourceAttrSet("oktawave_scheduler.test-scheduler", "creation_date"),
+				),
+			},
+			{
+				Config: testAccSchedulerConfig("test-scheduler-renamed", "2030-01-02T19:00:00+01:00"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("oktawave_scheduler.test-scheduler", "name", "test-scheduler-renamed"),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckSchedulerDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(*ClientConfig).odkClient
+	auth := testAccProvider.Meta().(*ClientConfig).odkAuth
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "oktawave_scheduler" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(rs.Primary.ID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Failed to parse resource id %s.", rs.Primary.ID)
+		}
+
+		_, resp, err := client.OCISchedulersApi.InstanceSchedulersGet(*auth, int32(id), nil)
+		if err == nil {
+			return fmt.Errorf("Scheduler with id %d not destroyed correctly.", id)
+		}
+		if resp == nil || (resp.StatusCode != 404 && resp.StatusCode != 403) {
+			return fmt.Errorf("Failed to call for scheduler. Caused by: %s.", err)
+		}
+	}
+	return testAccCheckInstanceDatasourceDestroy(s)
+}
@@ -19,9 +19,10 @@ func resourceTemplate() *schema.Resource {
 		UpdateContext: resourceTemplateUpdate,
 		DeleteContext: resourceTemplateDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importStatePassthroughWithDeletionProtection,
 		},
 		Schema: map[string]*schema.Schema{
+			"deletion_protection": deletionProtectionSchema(),
 			"instance_id": { // write only
 				Type:     schema.TypeInt,
 				Required: true,
@@ -115,6 +116,7 @@ func resourceTemplate() *schema.Resource {
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(45 * time.Minute),
 		},
+		Description: "Template created by converting instance. Converted instance ceases to exist, oktawave_instance resource drops it from state on next refresh and its definition should be removed.",
 	}
 }
 
@@ -246,6 +248,10 @@ func resourceTemplateUpdate(ctx context.Context, d *schema.ResourceData, m inter
 func resourceTemplateDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	tflog.Info(ctx, "deleting template")
 
+	if diags := checkDeletionProtection(d); diags != nil {
+		return diags
+	}
+
 	client := m.(*ClientConfig).odkClient
 	auth := m.(*ClientConfig).odkAuth
 
@@ -6,6 +6,7 @@ import (
 	"math"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-log/tflog"
@@ -125,34 +126,34 @@ func attachIpById(client odk.APIClient, auth *context.Context, instanceId int32,
 	return ticket, nil, err
 }
 
-// func detachIp(client odk.APIClient, auth *context.Context, instanceId int32, ip string) (odk.Ticket, *http.Response, error) {
-// 	tflog.Debug(context.Background(), "calling ODK FloatingIPsApi.FloatingIpsPostDetachIpTicket")
-// 	ticket, resp, err := client.FloatingIPsApi.FloatingIpsPostDetachIpTicket(*auth, ip, instanceId)
-// 	if err != nil {
-// 		if resp != nil && resp.StatusCode == http.StatusNotFound {
-// 			return ticket, resp, fmt.Errorf("instance id %v or IP %v was not found", instanceId, ip)
-// 		}
-// 		return ticket, resp, fmt.Errorf("ODK Error in FloatingIPsApi.FloatingIpsPostDetachIpTicket. %v", err)
-// 	}
-// 	ticket, err = waitForTicket(client, auth, ticket)
-// 	return ticket, nil, err
-// }
-
-// func attachIp(client odk.APIClient, auth *context.Context, instanceId int32, ip string) (odk.Ticket, *http.Response, error) {
-// 	localOptions := map[string]interface{}{
-// 		"ipV4": ip,
-// 	}
-// 	tflog.Debug(context.Background(), "calling ODK FloatingIPsApi.FloatingIpsPostAttachIpTicket")
-// 	ticket, resp, err := client.FloatingIPsApi.FloatingIpsPostAttachIpTicket(*auth, instanceId, localOptions)
-// 	if err != nil {
-// 		if resp != nil && resp.StatusCode == http.StatusNotFound {
-// 			return ticket, resp, fmt.Errorf("instance id %v or IP %v was not found", instanceId, ip)
-// 		}
-// 		return ticket, resp, fmt.Errorf("ODK Error in FloatingIPsApi.FloatingIpsPostAttachIpTicket. %v", err)
-// 	}
-// 	ticket, err = waitForTicket(client, auth, ticket)
-// 	return ticket, nil, err
-// }
+func detachIp(client odk.APIClient, auth *context.Context, instanceId int32, ip string) (odk.Ticket, *http.Response, error) {
+	tflog.Debug(context.Background(), "calling ODK FloatingIPsApi.FloatingIpsPostDetachIpTicket")
+	ticket, resp, err := client.FloatingIPsApi.FloatingIpsPostDetachIpTicket(*auth, ip, instanceId)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return ticket, resp, fmt.Errorf("instance id %v or IP %v was not found", instanceId, ip)
+		}
+		return ticket, resp, fmt.Errorf("ODK Error in FloatingIPsApi.FloatingIpsPostDetachIpTicket. %v", err)
+	}
+	ticket, err = waitForTicket(client, auth, ticket)
+	return ticket, nil, err
+}
+
+func attachIp(client odk.APIClient, auth *context.Context, instanceId int32, ip string) (odk.Ticket, *http.Response, error) {
+	localOptions := map[string]interface{}{
+		"ipV4": ip,
+	}
+	tflog.Debug(context.Background(), "calling ODK FloatingIPsApi.FloatingIpsPostAttachIpTicket")
+	ticket, resp, err := client.FloatingIPsApi.FloatingIpsPostAttachIpTicket(*auth, instanceId, localOptions)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return ticket, resp, fmt.Errorf("instance id %v or IP %v was not found", instanceId, ip)
+		}
+		return ticket, resp, fmt.Errorf("ODK Error in FloatingIPsApi.FloatingIpsPostAttachIpTicket. %v", err)
+	}
+	ticket, err = waitForTicket(client, auth, ticket)
+	return ticket, nil, err
+}
 
 func getConnectionInstanceIds(connections []odk.DiskConnection) []int {
 	connectionIds := make([]int, len(connections))
@@ -322,3 +323,82 @@ func filter[T any](items []T, fn func(item T) bool) []T {
 	}
 	return filteredItems
 }
+
+// ipListCache keeps account-wide IP listing for the whole provider run, so refreshing many instances lists IPs once.
+// It has to be invalidated after any change of IP assignments.
+type ipListCache struct {
+	mutex      sync.Mutex
+	loaded     bool
+	byInstance map[int32][]odk.Ip
+}
+
+// getInstanceIps returns IPs attached to instance, keeping order returned by API.
+func (c *ipListCache) getInstanceIps(ctx context.Context, client odk.APIClient, auth *context.Context, instanceId int32) ([]odk.Ip, error) {
+	if c == nil {
+		tflog.Debug(ctx, "calling ODK FloatingIPsApi.FloatingIpsGetIps", map[string]interface{}{"instanceId": instanceId})
+		params := map[string]interface{}{
+			"instanceId": instanceId,
+			"pageSize":   int32(math.MaxInt16),
+		}
+		ips, _, err := client.FloatingIPsApi.FloatingIpsGetIps(*auth, params)
+		if err != nil {
+			return nil, fmt.Errorf("ODK Error in FloatingIPsApi.FloatingIpsGetIps. %s", err)
+		}
+		return ips.Items, nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.loaded {
+		tflog.Debug(ctx, "calling ODK FloatingIPsApi.FloatingIpsGetIps")
+		params := map[string]interface{}{
+			"pageSize": int32(math.MaxInt16),
+		}
+		ips, _, err := client.FloatingIPsApi.FloatingIpsGetIps(*auth, params)
+		if err != nil {
+			return nil, fmt.Errorf("ODK Error in FloatingIPsApi.FloatingIpsGetIps. %s", err)
+		}
+		c.byInstance = make(map[int32][]odk.Ip)
+		for _, ip := range ips.Items {
+			if ip.Instance != nil {
+				c.byInstance[ip.Instance.Id] = append(c.byInstance[ip.Instance.Id], ip)
+			}
+		}
+		c.loaded = true
+	}
+	return c.byInstance[instanceId], nil
+}
+
+func (c *ipListCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.loaded = false
+	c.byInstance = nil
+}
+
+func deletionProtectionSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "When true, destroying this resource fails. Set it to false and apply before destroying.",
+	}
+}
+
+func checkDeletionProtection(d *schema.ResourceData) diag.Diagnostics {
+	if d.Get("deletion_protection").(bool) {
+		return diag.Errorf("Resource %v is protected by deletion_protection. Set deletion_protection to false and apply it before destroying this resource.", d.Id())
+	}
+	return nil
+}
+
+// importStatePassthroughWithDeletionProtection stores default of deletion_protection, which can't be read from API.
+func importStatePassthroughWithDeletionProtection(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	if d.Set("deletion_protection", false) != nil {
+		return nil, fmt.Errorf("can't set deletion protection")
+	}
+	return []*schema.ResourceData{d}, nil
+}